@@ -0,0 +1,418 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+)
+
+// Allotment is a block of seats of a section reserved for
+// sponsors, press or artists, that never goes on public sale.
+// The tickets of an allotment are issued for free by cc-ticket
+type Allotment struct {
+	Name    string `json:"name"`
+	Section string `json:"section"`
+
+	// session whose seats are reserved, empty
+	// for the allotments of the event sections
+	Session string `json:"session"`
+
+	// identity allowed to issue the
+	// tickets of the allotment
+	AssigneeMSPID    string `json:"assignee_msp_id"`
+	AssigneeUsername string `json:"assignee_username"`
+
+	Tickets       int `json:"tickets"`
+	IssuedTickets int `json:"issued_tickets"`
+
+	// true once the allotment was removed while it had issued
+	// tickets. Closed allotments only hold the issued seats, so
+	// the seats of their released tickets go to the public sale
+	Closed bool `json:"closed"`
+}
+
+// AddAllotment reserves "tickets" seats of the section "sectionName"
+// for the identity "assigneeMSPID"/"assigneeUsername". The seats are
+// subtracted from the tickets available for the public sale, so they
+// must not be sold yet. Allotments can be added while the event is in
+// status "draft" or "on_sale". If the event has sessions, the seats
+// are reserved in every session. The caller must be the organizer or a
+// delegate with permission "manage_sections"
+//
+// Params
+// * - eventID          -> uuid format
+// * - sectionName      -> unique name that identifies the section in the event
+// * - name             -> unique name that identifies the allotment in the section
+// * - assigneeMSPID    -> MSP of the identity that issues the tickets
+// * - assigneeUsername -> username of the identity that issues the tickets
+// * - tickets          -> amount of seats reserved
+//
+// The return value can be:
+// * - the allotment created serialized in JSON format
+// * - error in case some conditions to add the allotment are not fulfilled
+func (c *Contract) AddAllotment(ctx common.ITickenTxContext, eventID, sectionName, name, assigneeMSPID, assigneeUsername, tickets string) (*Allotment, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	section := event.getSection(sectionName)
+	if section == nil {
+		return nil, ccErr("section %s doest not exist in event %s", sectionName, eventID)
+	}
+
+	if err := checkPermission(ctx, event, PermissionManageSections); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft && event.Status != EventStatusOnSale {
+		return nil, ccErr("allotments can not be added to events on status %s", event.Status)
+	}
+
+	if len(name) == 0 {
+		return nil, ccErr("allotment name is required")
+	}
+	if section.getAllotment(name) != nil {
+		return nil, ccErr("allotment with name %s already exists in section %s", name, sectionName)
+	}
+
+	if len(assigneeMSPID) == 0 || len(assigneeUsername) == 0 {
+		return nil, ccErr("assignee msp id and username are required")
+	}
+
+	ticketsParsed, err := strconv.Atoi(tickets)
+	if err != nil {
+		return nil, ccErr("error converting allotment tickets: %v", err)
+	}
+	if ticketsParsed <= 0 {
+		return nil, ccErr("invalid allotment tickets value %d - tickets must be greater than 0", ticketsParsed)
+	}
+
+	allotment := Allotment{
+		Name:             name,
+		Section:          section.Name,
+		AssigneeMSPID:    assigneeMSPID,
+		AssigneeUsername: assigneeUsername,
+		Tickets:          ticketsParsed,
+		IssuedTickets:    0,
+	}
+
+	for _, sectionCopy := range event.getSectionCopies(sectionName) {
+		waitlist, err := getWaitlist(ctx, eventID, sectionCopy.Session, sectionName)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+
+		// the seats offered to waitlisted
+		// owners are already reserved
+		availableTickets := waitlist.availableTickets(sectionCopy)
+		if ticketsParsed > availableTickets {
+			return nil, ccErr("section %s has only %d tickets available", sectionName, availableTickets)
+		}
+
+		allotmentCopy := allotment
+		allotmentCopy.Session = sectionCopy.Session
+		sectionCopy.Allotments = append(sectionCopy.Allotments, &allotmentCopy)
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &allotment, nil
+}
+
+// RemoveAllotment returns the seats of the allotment "name" that were
+// not issued to the public sale. If the allotment has issued tickets,
+// it is kept closed with its seats reduced to the issued ones, and
+// those seats go to the public sale as their tickets are released,
+// so no more tickets can be issued from it. The allotment
+// is removed from every session of the event. The caller must be the
+// organizer or a delegate with permission "manage_sections"
+//
+// Params
+// * - eventID     -> uuid format
+// * - sectionName -> unique name that identifies the section in the event
+// * - name        -> unique name that identifies the allotment in the section
+//
+// The return value can be:
+// * - error in case the allotment can not be removed
+func (c *Contract) RemoveAllotment(ctx common.ITickenTxContext, eventID, sectionName, name string) error {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionManageSections); err != nil {
+		return err // this error is already formatted
+	}
+
+	section := event.getSection(sectionName)
+	if section == nil {
+		return ccErr("section %s doest not exist in event %s", sectionName, eventID)
+	}
+
+	// for events with sessions, the tickets are issued from the
+	// allotments of the sessions, and the one of the event is
+	// only the template of the sessions added later
+	saleSections := []*Section{section}
+	if len(event.Sessions) > 0 {
+		saleSections = make([]*Section, 0)
+		for _, session := range event.Sessions {
+			sessionSection, err := event.getSessionSection(session.SessionID, sectionName)
+			if err != nil {
+				return err // this error is already formatted
+			}
+			saleSections = append(saleSections, sessionSection)
+		}
+	}
+
+	found := section.getAllotment(name) != nil
+	open := false
+	for _, saleSection := range saleSections {
+		allotment := saleSection.getAllotment(name)
+		if allotment != nil {
+			found = true
+			open = open || !allotment.Closed
+		}
+	}
+	if !found {
+		return ccErr("allotment %s doest not exist in section %s", name, sectionName)
+	}
+	if !open {
+		return ccErr("allotment %s was already removed", name)
+	}
+
+	sectionCopies := event.getSectionCopies(sectionName)
+	for _, sectionCopy := range sectionCopies {
+		sectionCopy.removeAllotment(name)
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return err // this error is already formatted
+	}
+
+	// the seats returned to the public sale
+	// go first to the waitlisted owners
+	if _, err := offerReleasedSeats(ctx, event, sectionCopies...); err != nil {
+		return err // this error is already formatted
+	}
+
+	return nil
+}
+
+// ListAllotments returns the allotments of all the sections
+// of the event with ID "eventID" and their usage, including
+// the ones of every session of the event
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the list of allotments (possibly empty)
+// * - error in case of the event is not found
+func (c *Contract) ListAllotments(ctx common.ITickenTxContext, eventID string) ([]*Allotment, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	allotments := make([]*Allotment, 0)
+	for _, section := range event.Sections {
+		allotments = append(allotments, section.Allotments...)
+	}
+	for _, session := range event.Sessions {
+		for _, section := range session.Sections {
+			allotments = append(allotments, section.Allotments...)
+		}
+	}
+
+	return allotments, nil
+}
+
+// GetAllotment returns the allotment "name" of the section
+// "sectionName" with the amount of tickets issued from it
+//
+// Params
+// * - eventID     -> uuid format
+// * - sessionID   -> uuid format (must be empty for events without sessions)
+// * - sectionName -> unique name that identifies the section in the event
+// * - name        -> unique name that identifies the allotment in the section
+//
+// The return value can be:
+// * - the allotment serialized in JSON format
+// * - error in case the allotment is not found
+func (c *Contract) GetAllotment(ctx common.ITickenTxContext, eventID, sessionID, sectionName, name string) (*Allotment, error) {
+	_, section, err := c.getEventSection(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	allotment := section.getAllotment(name)
+	if allotment == nil {
+		return nil, ccErr("allotment %s doest not exist in section %s", name, sectionName)
+	}
+
+	return allotment, nil
+}
+
+// IssueAllotmentTicket increases in one the ticket count of the section
+// "sectionName" and of its allotment "name". This is called by cc-ticket
+// to issue a complimentary ticket, which is not charged. The event, and
+// the session "sessionID" if the event has sessions, must be on sale,
+// with its sales closed or running. The caller must be the allotment
+// assignee or the event organizer
+//
+// Params
+// * - eventID     -> uuid format
+// * - sessionID   -> uuid format (must be empty for events without sessions)
+// * - sectionName -> unique name that identifies the section in the event
+// * - name        -> unique name that identifies the allotment in the section
+//
+// The return value can be:
+// * - the sale with zero price serialized in JSON format
+// * - error in case the allotment has no seats left
+func (c *Contract) IssueAllotmentTicket(ctx common.ITickenTxContext, eventID, sessionID, sectionName, name string) (*TicketSale, error) {
+	event, section, err := c.getEventSection(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	status := event.saleStatus(section)
+	if status != EventStatusOnSale && status != EventStatusSalesClosed && status != EventStatusRunning {
+		return nil, ccErr("tickets of event on status %s can not be issued", status)
+	}
+
+	if err := c.checkOrganizerNotSuspended(ctx, event.MSPID, event.OrganizerUsername); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	allotment := section.getAllotment(name)
+	if allotment == nil {
+		return nil, ccErr("allotment %s doest not exist in section %s", name, sectionName)
+	}
+	if allotment.Closed {
+		return nil, ccErr("allotment %s was removed", name)
+	}
+
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return nil, ccErr("could not get context identity: %v", err)
+	}
+
+	isAssignee := allotment.AssigneeMSPID == mspID && allotment.AssigneeUsername == username
+	if !isAssignee && !event.isOwner(mspID, username) {
+		return nil, ccErr("only the assignee of the allotment %s or the organizer can issue its tickets", name)
+	}
+
+	if allotment.IssuedTickets >= allotment.Tickets {
+		return nil, ccErr("allotment %s has no tickets left", name)
+	}
+
+	allotment.IssuedTickets += 1
+	section.SoldTickets += 1
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &TicketSale{
+		EventID:        event.EventID,
+		Session:        section.Session,
+		Section:        section.Name,
+		Allotment:      allotment.Name,
+		Price:          0,
+		WaitlistOffers: make([]*WaitlistOffer, 0),
+	}, nil
+}
+
+func (section *Section) getAllotment(name string) *Allotment {
+	for _, allotment := range section.Allotments {
+		if allotment.Name == name {
+			return allotment
+		}
+	}
+	return nil
+}
+
+// removeAllotment returns the seats of the allotment "name" that
+// were not issued. The allotment is kept closed with its seats
+// reduced to the issued ones if it has issued tickets
+func (section *Section) removeAllotment(name string) {
+	allotment := section.getAllotment(name)
+	if allotment == nil {
+		return
+	}
+
+	if allotment.IssuedTickets > 0 {
+		allotment.Tickets = allotment.IssuedTickets
+		allotment.Closed = true
+		return
+	}
+
+	section.deleteAllotment(allotment)
+}
+
+// releaseAllotmentTicket returns to the allotment the seat of a ticket
+// issued from it. The seats of closed allotments go to the public sale
+// instead, and the allotment is deleted once it has no seats left
+func (section *Section) releaseAllotmentTicket(allotment *Allotment) {
+	allotment.IssuedTickets -= 1
+	if !allotment.Closed {
+		return
+	}
+
+	allotment.Tickets -= 1
+	if allotment.Tickets == 0 {
+		section.deleteAllotment(allotment)
+	}
+}
+
+// repairAllotment sets the amount of tickets issued from the allotment.
+// Closed allotments only keep the seats of their issued tickets, and the
+// seats of open ones grow if more tickets were issued than they had
+func (section *Section) repairAllotment(allotment *Allotment, issuedTickets int) {
+	allotment.IssuedTickets = issuedTickets
+	if allotment.Closed || issuedTickets > allotment.Tickets {
+		allotment.Tickets = issuedTickets
+	}
+
+	if allotment.Closed && allotment.Tickets == 0 {
+		section.deleteAllotment(allotment)
+	}
+}
+
+func (section *Section) deleteAllotment(allotment *Allotment) {
+	remainingAllotments := make([]*Allotment, 0)
+	for _, a := range section.Allotments {
+		if a != allotment {
+			remainingAllotments = append(remainingAllotments, a)
+		}
+	}
+	section.Allotments = remainingAllotments
+}
+
+// allottedTickets returns the amount of seats reserved
+// by the allotments that were not yet issued
+func (section *Section) allottedTickets() int {
+	allottedTickets := 0
+	for _, allotment := range section.Allotments {
+		allottedTickets += allotment.Tickets - allotment.IssuedTickets
+	}
+	return allottedTickets
+}
+
+// publicSoldTickets returns the amount of tickets of the
+// section that were sold, without the allotment tickets
+func (section *Section) publicSoldTickets() int {
+	publicSoldTickets := section.SoldTickets
+	for _, allotment := range section.Allotments {
+		publicSoldTickets -= allotment.IssuedTickets
+	}
+	return publicSoldTickets
+}
+
+// publicAvailableTickets returns the amount of tickets of the
+// section that can be sold, without the seats of the allotments
+func (section *Section) publicAvailableTickets() int {
+	return section.TotalTickets - section.SoldTickets - section.allottedTickets()
+}
@@ -1,7 +1,6 @@
 package contract
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
@@ -28,6 +27,10 @@ const (
 	// event that is published for sale
 	EventStatusOnSale EventStatus = "on_sale"
 
+	// EventStatusSalesClosed is the status of an event
+	// whose sales were stopped before it starts
+	EventStatusSalesClosed EventStatus = "sales_closed"
+
 	// EventStatusRunning is the status of an
 	// event that is currently happening
 	EventStatusRunning EventStatus = "running"
@@ -44,9 +47,47 @@ type Event struct {
 	Sections []*Section  `json:"sections"`
 	Status   EventStatus `json:"status"`
 
+	// venue registered in cc-venue where
+	// the event takes place
+	VenueID string `json:"venue_id"`
+
+	// moment when the event went to
+	// status "running", zero before that
+	StartedAt time.Time `json:"started_at"`
+
+	// phases in which the tickets are released. When
+	// empty, all sections are on sale while the event
+	// is in status "on_sale"
+	SalePhases []*SalePhase `json:"sale_phases"`
+
 	// identity of the event and auditory
 	MSPID             string `json:"msp_id"`
 	OrganizerUsername string `json:"organizer_username"`
+
+	// identities that can act on behalf of
+	// the organizer with limited permissions
+	Delegates []*Delegate `json:"delegates"`
+
+	// identities allowed to scan the tickets
+	Validators []*Validator `json:"validators"`
+
+	// conditions to refund the tickets. Nil
+	// means that the event has no refunds
+	RefundPolicy *RefundPolicy `json:"refund_policy"`
+
+	// times used to advance the status of the event
+	// automatically. Nil means that the event is only
+	// started and finished by its organizer
+	Schedule *Schedule `json:"schedule"`
+
+	// series to which the event belongs,
+	// empty for standalone events
+	SeriesID string `json:"series_id"`
+
+	// performances of the event on different dates.
+	// When empty, the tickets are sold for the event
+	// itself using the counters of its sections
+	Sessions []*Session `json:"sessions"`
 }
 
 type Section struct {
@@ -55,21 +96,39 @@ type Section struct {
 	TicketPrice  float64 `json:"ticket_price"`
 	TotalTickets int     `json:"total_tickets"`
 	SoldTickets  int     `json:"sold_tickets"`
+
+	// session whose tickets are counted in the
+	// section, empty for the sections of the event
+	Session string `json:"session"`
+
+	// optional area of the venue
+	// where the section is located
+	Area string `json:"area"`
+
+	// optional tiers that override the ticket
+	// price according to the tickets sold
+	PriceTiers []*PriceTier `json:"price_tiers"`
+
+	// blocks of seats reserved for sponsors,
+	// press or artists, not sold to the public
+	Allotments []*Allotment `json:"allotments"`
 }
 
 // Create a new event without any sections in the blockchain and returns
 // its value. The event is created as with status "EventStatusDraft", so it
-// can be updated and sections can be added on following transactions
+// can be updated and sections can be added on following transactions.
+// The caller must be a verified organizer
 //
 // Params
 // * - eventID -> uuid format
 // * - name    -> string
 // * - date    -> RFC3339 format (2006-01-02T15:04:05Z07:00)
+// * - venueID -> uuid format (must be registered in cc-venue)
 //
 // The return value can be:
 // * - the event created serialized in JSON format
 // * - error in case some conditions to create the event are not fulfilled
-func (c *Contract) Create(ctx common.ITickenTxContext, eventID, name, date string) (*Event, error) {
+func (c *Contract) Create(ctx common.ITickenTxContext, eventID, name, date, venueID string) (*Event, error) {
 	existentEvent, err := c.GetEvent(ctx, eventID)
 	if existentEvent != nil {
 		return nil, ccErr("event with ID %s already exists", eventID)
@@ -84,17 +143,32 @@ func (c *Contract) Create(ctx common.ITickenTxContext, eventID, name, date strin
 		return nil, ccErr("error parsing event id: %v", err)
 	}
 
+	venue, err := getVenue(ctx, venueID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
 	mspID, orgUsername, err := ctx.GetContextIdentity()
 	if err != nil {
 		return nil, ccErr("could not get context identity: %v", err)
 	}
 
+	if err := c.checkOrganizerEnabled(ctx, mspID, orgUsername); err != nil {
+		return nil, err // this error is already formatted
+	}
+
 	event := Event{
 		EventID:  eventIDParsed.String(),
 		Name:     name,
-		Date:     parsedDate,
+		Date:     storedDate(parsedDate),
 		Sections: make([]*Section, 0),
 		Status:   EventStatusDraft,
+		VenueID:  venue.VenueID,
+
+		SalePhases: make([]*SalePhase, 0),
+		Delegates:  make([]*Delegate, 0),
+		Validators: make([]*Validator, 0),
+		Sessions:   make([]*Session, 0),
 
 		// this values will be validated from
 		// the values that the chaincode notify us
@@ -102,13 +176,8 @@ func (c *Contract) Create(ctx common.ITickenTxContext, eventID, name, date strin
 		OrganizerUsername: orgUsername,
 	}
 
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return nil, ccErr("failed to serialize event: %v", err)
-	}
-
-	if err := ctx.GetStub().PutState(eventID, eventJSON); err != nil {
-		return nil, ccErr("failed to updated  tate: %v", err)
+	if err := c.putEvent(ctx, &event); err != nil {
+		return nil, err // this error is already formatted
 	}
 
 	return &event, nil
@@ -124,16 +193,21 @@ func (c *Contract) Create(ctx common.ITickenTxContext, eventID, name, date strin
 // * - name    -> section name (must be unique)
 // * - totalTickets
 // * - ticketPrice
+// * - area    -> name of the venue area where the section is located (can be empty)
 //
 // The return value can be:
 // * - the section added serialized in JSON format
 // * - error in case some conditions to add the section are not fulfilled
-func (c *Contract) AddSection(ctx common.ITickenTxContext, eventID, name, totalTickets, ticketPrice string) (*Section, error) {
+func (c *Contract) AddSection(ctx common.ITickenTxContext, eventID, name, totalTickets, ticketPrice, area string) (*Section, error) {
 	event, err := c.GetEvent(ctx, eventID)
 	if err != nil {
 		return nil, err // this error is already formatted
 	}
 
+	if err := checkPermission(ctx, event, PermissionManageSections); err != nil {
+		return nil, err // this error is already formatted
+	}
+
 	if event.Status != EventStatusDraft {
 		return nil, ccErr("event is not in status draft")
 	}
@@ -167,17 +241,25 @@ func (c *Contract) AddSection(ctx common.ITickenTxContext, eventID, name, totalT
 		SoldTickets:  0,
 		TotalTickets: totalTicketsParsed,
 		TicketPrice:  twoDecimalsPrice,
+		Area:         area,
+		PriceTiers:   make([]*PriceTier, 0),
+		Allotments:   make([]*Allotment, 0),
 	}
 
 	event.Sections = append(event.Sections, &newSection)
 
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return nil, ccErr("failed to serialize event; %v", err)
+	// the layout of the event is shared
+	// by all its sessions
+	for _, session := range event.Sessions {
+		session.Sections = append(session.Sections, newSection.copyFor(session.SessionID))
 	}
 
-	if err := ctx.GetStub().PutState(eventID, eventJSON); err != nil {
-		return nil, ccErr("failed to updated the state: %v", err)
+	if err := checkVenueCapacity(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
 	}
 
 	return &newSection, nil
@@ -186,7 +268,9 @@ func (c *Contract) AddSection(ctx common.ITickenTxContext, eventID, name, totalT
 // Sell sets the previously created event to be on status
 // "on sale".  From this moment, we can start issuing tickets for
 // this event. In addition, this status blocks any modification or change
-// in the event, including adding sections
+// in the event, including adding sections. The event can not be
+// published if its sections exceed the capacity of the venue or
+// if its organizer is not verified
 //
 // Params
 // * - eventID -> uuid format
@@ -194,33 +278,7 @@ func (c *Contract) AddSection(ctx common.ITickenTxContext, eventID, name, totalT
 // The return value can be:
 // * - error in case the event cant transition to state "on_sale"
 func (c *Contract) Sell(ctx common.ITickenTxContext, eventID string) error {
-	event, err := c.GetEvent(ctx, eventID)
-	if err != nil {
-		return err // this error is already formatted
-	}
-
-	if event.Status == EventStatusOnSale {
-		return ccErr("event %s already is on status", event.EventID, EventStatusOnSale)
-	}
-
-	if event.Status != EventStatusDraft {
-		return ccErr("event cant go from %s to %s", event.Status, EventStatusDraft)
-	}
-
-	// update status from
-	// EventStatusOnSale -> EventStatusOnSale
-	event.Status = EventStatusOnSale
-
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return ccErr("failed to serialize event: %v", err)
-	}
-
-	if err := ctx.GetStub().PutState(eventID, eventJSON); err != nil {
-		return ccErr("failed to updated  tate: %v", err)
-	}
-
-	return nil
+	return c.fireEventTransition(ctx, eventID, transitionSell)
 }
 
 // Start sets the previously created event to be on status
@@ -234,33 +292,7 @@ func (c *Contract) Sell(ctx common.ITickenTxContext, eventID string) error {
 // The return value can be:
 // * - error in case the event cant transition to state "running"
 func (c *Contract) Start(ctx common.ITickenTxContext, eventID string) error {
-	event, err := c.GetEvent(ctx, eventID)
-	if err != nil {
-		return err // this error is already formatted
-	}
-
-	if event.Status == EventStatusRunning {
-		return ccErr("event %s already is on status %s", event.EventID, EventStatusRunning)
-	}
-
-	if event.Status != EventStatusOnSale {
-		return ccErr("event cant go from %s to %s", event.Status, EventStatusRunning)
-	}
-
-	// update status from
-	// EventStatusOnSale -> EventStatusOnSale
-	event.Status = EventStatusRunning
-
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return ccErr("failed to serialize event: %v", err)
-	}
-
-	if err := ctx.GetStub().PutState(eventID, eventJSON); err != nil {
-		return ccErr("failed to updated  tate: %v", err)
-	}
-
-	return nil
+	return c.fireEventTransition(ctx, eventID, transitionStart)
 }
 
 // Finish sets the previously created event to be on status
@@ -272,35 +304,9 @@ func (c *Contract) Start(ctx common.ITickenTxContext, eventID string) error {
 // * - eventID -> uuid format
 //
 // The return value can be:
-// * - error in case the event cant transition to state "running"
+// * - error in case the event cant transition to state "finished"
 func (c *Contract) Finish(ctx common.ITickenTxContext, eventID string) error {
-	event, err := c.GetEvent(ctx, eventID)
-	if err != nil {
-		return err // this error is already formatted
-	}
-
-	if event.Status == EventStatusFinished {
-		return ccErr("event %s already is on status %s", event.EventID, EventStatusFinished)
-	}
-
-	if event.Status != EventStatusRunning {
-		return ccErr("event cant go from %s to %s", event.Status, EventStatusRunning)
-	}
-
-	// update status from
-	// EventStatusRunning -> EventStatusRunning
-	event.Status = EventStatusRunning
-
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return ccErr("failed to serialize event: %v", err)
-	}
-
-	if err := ctx.GetStub().PutState(eventID, eventJSON); err != nil {
-		return ccErr("failed to updated  tate: %v", err)
-	}
-
-	return nil
+	return c.fireEventTransition(ctx, eventID, transitionFinish)
 }
 
 // GetEvent returns the event information of the event with id "eventID".
@@ -320,7 +326,7 @@ func (c *Contract) GetEvent(ctx common.ITickenTxContext, eventID string) (*Event
 	}
 
 	var event Event
-	if err := json.Unmarshal(eventJSON, &event); err != nil {
+	if _, err := eventSchema.Unmarshal(eventJSON, &event); err != nil {
 		return nil, ccErr("failed to deserialize event: %v", err)
 	}
 
@@ -329,53 +335,114 @@ func (c *Contract) GetEvent(ctx common.ITickenTxContext, eventID string) (*Event
 
 // SellTicket increase in one the ticket count on the
 // section with "sectionName" of the event with "eventID"
-// The event must be in the state "OnSale" in order to success
+// The event must be in the state "OnSale" in order to success.
+// If the event has sessions, the ticket is counted in the session
+// with "sessionID", which must also be on sale.
+// If the event has sale phases, one of them must be open for
+// the section at the transaction time and have remaining quota.
+// The price charged is computed from the price tiers of the
+// section, falling back to the section ticket price. Tickets of
+// events whose organizer is suspended can not be sold. Seats offered
+// to waitlisted owners can only be bought by them, and the seats of
+// the allotments are never sold
 //
 // Params
 // * - eventID -> uuid format
+// * - sessionID -> uuid format (must be empty for events without sessions)
 // * - sectionName -> unique name that identifies the section in the event
+// * - ownerID -> uuid format (owner that buys the ticket)
 //
 // The return value can be:
+// * - the sale with the price charged serialized in JSON format
 // * - error in case of the event is not found
-func (c *Contract) SellTicket(ctx common.ITickenTxContext, eventID string, sectionName string) error {
+func (c *Contract) SellTicket(ctx common.ITickenTxContext, eventID string, sessionID string, sectionName string, ownerID string) (*TicketSale, error) {
 	event, err := c.GetEvent(ctx, eventID)
 	if err != nil {
-		return err // this error is already formatted
+		return nil, err // this error is already formatted
 	}
 
 	if event.Status != EventStatusOnSale {
-		return ccErr("event not on sale")
+		return nil, ccErr("event not on sale")
 	}
 
-	var foundSection *Section
-	for _, section := range event.Sections {
-		if section.Name == sectionName {
-			foundSection = section
-			break
-		}
+	if err := c.checkOrganizerNotSuspended(ctx, event.MSPID, event.OrganizerUsername); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	foundSection, err := event.getSessionSection(sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.saleStatus(foundSection) != EventStatusOnSale {
+		return nil, ccErr("session %s not on sale", sessionID)
+	}
+
+	if foundSection.publicAvailableTickets() <= 0 {
+		return nil, ccErr("section %s is full", sectionName)
 	}
 
-	if foundSection == nil {
-		return ccErr("section %s doest not exist in event %s", sectionName, eventID)
+	waitlistOffers, err := claimWaitlistSeat(ctx, event, foundSection, ownerID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	sale := TicketSale{
+		EventID: event.EventID,
+		Session: foundSection.Session,
+		Section: foundSection.Name,
+		Price:   foundSection.currentPrice(now),
+
+		WaitlistOffers: waitlistOffers,
 	}
 
-	if foundSection.SoldTickets == foundSection.TotalTickets {
-		return ccErr("section %s is full", sectionName)
+	// events without sale phases can sell
+	// any section during the whole sale
+	if len(event.SalePhases) > 0 {
+		phase := event.getSalePhaseFor(sectionName, now)
+		if phase == nil {
+			return nil, ccErr("there is no sale phase open for section %s", sectionName)
+		}
+		phase.SoldTickets += 1
+		sale.SalePhase = phase.Name
 	}
 
 	foundSection.SoldTickets += 1
-	eventJSON, err := json.Marshal(event)
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &sale, nil
+}
+
+func (c *Contract) putEvent(ctx common.ITickenTxContext, event *Event) error {
+	eventJSON, err := eventSchema.Marshal(event)
 	if err != nil {
-		return ccErr("failed to deserialize event: %v", err)
+		return ccErr("failed to serialize event: %v", err)
 	}
 
-	if err := ctx.GetStub().PutState(eventID, eventJSON); err != nil {
+	if err := ctx.GetStub().PutState(event.EventID, eventJSON); err != nil {
 		return ccErr("failed to update ledger: %v", err)
 	}
 
 	return nil
 }
 
+func (event *Event) getSection(name string) *Section {
+	for _, section := range event.Sections {
+		if section.Name == name {
+			return section
+		}
+	}
+	return nil
+}
+
 func ccErr(format string, args ...any) error {
 	msg := fmt.Sprintf(format, args...)
 	return fmt.Errorf("[%s] | %s", Name, msg)
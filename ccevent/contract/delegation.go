@@ -0,0 +1,272 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+)
+
+type Permission string
+
+const (
+	// PermissionEditDraft allows to change the configuration
+	// of the event while it is in draft, such as the sale phases
+	PermissionEditDraft Permission = "edit_draft"
+
+	// PermissionManageSections allows to add sections
+	// to the event and to change their prices
+	PermissionManageSections Permission = "manage_sections"
+
+	// PermissionOpenSale allows to put the event on sale
+	PermissionOpenSale Permission = "open_sale"
+
+	// PermissionStartFinish allows to start and finish the event
+	PermissionStartFinish Permission = "start_finish"
+
+	// PermissionManageValidators allows to assign and
+	// revoke the validators that scan the tickets
+	PermissionManageValidators Permission = "manage_validators"
+)
+
+var allPermissions = []Permission{
+	PermissionEditDraft,
+	PermissionManageSections,
+	PermissionOpenSale,
+	PermissionStartFinish,
+	PermissionManageValidators,
+}
+
+// Delegate is an identity that acts on behalf of
+// the event organizer with a limited set of permissions
+type Delegate struct {
+	MSPID       string       `json:"msp_id"`
+	Username    string       `json:"username"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// GrantPermissions allows the identity "mspID"/"username" to perform
+// the operations covered by "permissions" on the event. Permissions
+// are added to the ones already granted to the identity. Only the
+// event organizer can grant permissions
+//
+// Params
+// * - eventID     -> uuid format
+// * - mspID       -> MSP of the delegate identity
+// * - username    -> username of the delegate identity
+// * - permissions -> edit_draft | manage_sections | open_sale | start_finish | manage_validators
+//
+// The return value can be:
+// * - the delegate updated serialized in JSON format
+// * - error in case the caller is not the organizer or a permission is invalid
+func (c *Contract) GrantPermissions(ctx common.ITickenTxContext, eventID, mspID, username string, permissions []string) (*Delegate, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkEventOwner(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if len(mspID) == 0 || len(username) == 0 {
+		return nil, ccErr("delegate msp id and username are required")
+	}
+
+	if event.isOwner(mspID, username) {
+		return nil, ccErr("the organizer already has all the permissions")
+	}
+
+	parsedPermissions, err := parsePermissions(permissions)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	delegate := event.getDelegate(mspID, username)
+	if delegate == nil {
+		delegate = &Delegate{
+			MSPID:       mspID,
+			Username:    username,
+			Permissions: make([]Permission, 0),
+		}
+		event.Delegates = append(event.Delegates, delegate)
+	}
+
+	for _, permission := range parsedPermissions {
+		if !delegate.hasPermission(permission) {
+			delegate.Permissions = append(delegate.Permissions, permission)
+		}
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return delegate, nil
+}
+
+// RevokePermissions removes the "permissions" granted to the identity
+// "mspID"/"username" on the event. If "permissions" is empty, all the
+// permissions are revoked and the identity is no longer a delegate.
+// Only the event organizer can revoke permissions
+//
+// Params
+// * - eventID     -> uuid format
+// * - mspID       -> MSP of the delegate identity
+// * - username    -> username of the delegate identity
+// * - permissions -> permissions to revoke (empty for all)
+//
+// The return value can be:
+// * - error in case the caller is not the organizer or the delegate is not found
+func (c *Contract) RevokePermissions(ctx common.ITickenTxContext, eventID, mspID, username string, permissions []string) error {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if err := checkEventOwner(ctx, event); err != nil {
+		return err // this error is already formatted
+	}
+
+	delegate := event.getDelegate(mspID, username)
+	if delegate == nil {
+		return ccErr("%s@%s is not a delegate of event %s", username, mspID, eventID)
+	}
+
+	parsedPermissions, err := parsePermissions(permissions)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if len(parsedPermissions) == 0 {
+		parsedPermissions = allPermissions
+	}
+
+	remainingPermissions := make([]Permission, 0)
+	for _, permission := range delegate.Permissions {
+		if !containsPermission(parsedPermissions, permission) {
+			remainingPermissions = append(remainingPermissions, permission)
+		}
+	}
+	delegate.Permissions = remainingPermissions
+
+	// delegates without permissions
+	// are removed from the event
+	if len(delegate.Permissions) == 0 {
+		remainingDelegates := make([]*Delegate, 0)
+		for _, d := range event.Delegates {
+			if d != delegate {
+				remainingDelegates = append(remainingDelegates, d)
+			}
+		}
+		event.Delegates = remainingDelegates
+	}
+
+	return c.putEvent(ctx, event)
+}
+
+// ListDelegates returns the identities that currently have
+// permissions delegated on the event
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the list of delegates (possibly empty)
+// * - error in case of the event is not found
+func (c *Contract) ListDelegates(ctx common.ITickenTxContext, eventID string) ([]*Delegate, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Delegates == nil {
+		return make([]*Delegate, 0), nil
+	}
+
+	return event.Delegates, nil
+}
+
+// checkPermission fails if the identity that submitted the
+// transaction is neither the event organizer nor a delegate
+// with the permission "permission" on the event
+func checkPermission(ctx common.ITickenTxContext, event *Event, permission Permission) error {
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return ccErr("could not get context identity: %v", err)
+	}
+
+	if event.isOwner(mspID, username) {
+		return nil
+	}
+
+	delegate := event.getDelegate(mspID, username)
+	if delegate == nil || !delegate.hasPermission(permission) {
+		return ccErr("%s@%s does not have permission %s on event %s", username, mspID, permission, event.EventID)
+	}
+
+	return nil
+}
+
+// checkEventOwner fails if the identity that submitted
+// the transaction is not the event organizer
+func checkEventOwner(ctx common.ITickenTxContext, event *Event) error {
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return ccErr("could not get context identity: %v", err)
+	}
+
+	if !event.isOwner(mspID, username) {
+		return ccErr("only the organizer of the event %s can perform this operation", event.EventID)
+	}
+
+	return nil
+}
+
+// checkEventOwnerOrAdmin fails if the identity that submitted the
+// transaction is neither the event organizer nor a platform admin
+func checkEventOwnerOrAdmin(ctx common.ITickenTxContext, event *Event) error {
+	isAdmin, err := ctx.IsPlatformAdmin()
+	if err != nil {
+		return ccErr("could not get context identity: %v", err)
+	}
+	if isAdmin {
+		return nil
+	}
+
+	return checkEventOwner(ctx, event)
+}
+
+func parsePermissions(permissions []string) ([]Permission, error) {
+	parsedPermissions := make([]Permission, 0)
+	for _, permission := range permissions {
+		if !containsPermission(allPermissions, Permission(permission)) {
+			return nil, ccErr("invalid permission %s", permission)
+		}
+		parsedPermissions = append(parsedPermissions, Permission(permission))
+	}
+	return parsedPermissions, nil
+}
+
+func containsPermission(permissions []Permission, permission Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (event *Event) isOwner(mspID, username string) bool {
+	return event.MSPID == mspID && event.OrganizerUsername == username
+}
+
+func (event *Event) getDelegate(mspID, username string) *Delegate {
+	for _, delegate := range event.Delegates {
+		if delegate.MSPID == mspID && delegate.Username == username {
+			return delegate
+		}
+	}
+	return nil
+}
+
+func (delegate *Delegate) hasPermission(permission Permission) bool {
+	return containsPermission(delegate.Permissions, permission)
+}
@@ -0,0 +1,90 @@
+package contract
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"strings"
+	"testing"
+)
+
+const delegationEventID = "22222222-2222-2222-2222-222222222222"
+
+var delegateIdentity = testIdentity{mspID: "Org1MSP", username: "helper"}
+
+func newDelegationEvent() *Event {
+	return &Event{
+		EventID:           delegationEventID,
+		Status:            EventStatusDraft,
+		MSPID:             organizerIdentity.mspID,
+		OrganizerUsername: organizerIdentity.username,
+		Sections:          make([]*Section, 0),
+		Delegates: []*Delegate{
+			{MSPID: delegateIdentity.mspID, Username: delegateIdentity.username, Permissions: []Permission{PermissionManageSections}},
+		},
+	}
+}
+
+func TestCheckPermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		identity   testIdentity
+		permission Permission
+		wantErr    bool
+	}{
+		{name: "organizer", identity: organizerIdentity, permission: PermissionOpenSale},
+		{name: "delegate with the permission", identity: delegateIdentity, permission: PermissionManageSections},
+		{name: "delegate without the permission", identity: delegateIdentity, permission: PermissionOpenSale, wantErr: true},
+		{name: "delegate username on other msp", identity: testIdentity{mspID: "Org2MSP", username: "helper"}, permission: PermissionManageSections, wantErr: true},
+		{name: "platform admin", identity: adminIdentity, permission: PermissionManageSections, wantErr: true},
+	}
+
+	event := newDelegationEvent()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(shimtest.NewMockStub(Name, nil), tt.identity)
+
+			err := checkPermission(ctx, event, tt.permission)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkPermission() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGrantPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity testIdentity
+		wantErr  string
+	}{
+		{name: "organizer", identity: organizerIdentity},
+		{name: "delegate", identity: delegateIdentity, wantErr: "only the organizer"},
+		{name: "platform admin", identity: adminIdentity, wantErr: "only the organizer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := shimtest.NewMockStub(Name, nil)
+			putTestEvent(t, stub, newDelegationEvent())
+
+			stub.MockTransactionStart("grant")
+			delegate, err := new(Contract).GrantPermissions(newTestContext(stub, tt.identity), delegationEventID,
+				delegateIdentity.mspID, delegateIdentity.username, []string{string(PermissionOpenSale)})
+			stub.MockTransactionEnd("grant")
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GrantPermissions() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GrantPermissions() error = %v", err)
+			}
+
+			// the new permission is added to the granted ones
+			if !delegate.hasPermission(PermissionManageSections) || !delegate.hasPermission(PermissionOpenSale) {
+				t.Errorf("delegate permissions = %v, want %s and %s", delegate.Permissions, PermissionManageSections, PermissionOpenSale)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package contract
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"testing"
+)
+
+// testIdentity is the identity that
+// submits the transactions of the tests
+type testIdentity struct {
+	mspID    string
+	username string
+
+	// value of the role attribute,
+	// empty if the identity has none
+	role string
+}
+
+var (
+	organizerIdentity     = testIdentity{mspID: "Org1MSP", username: "org1"}
+	adminIdentity         = testIdentity{mspID: "AdminMSP", username: "admin", role: common.RoleAdmin}
+	ticketServiceIdentity = testIdentity{mspID: "ServiceMSP", username: "service", role: common.RoleTicketService}
+)
+
+func (identity testIdentity) GetID() (string, error)    { return identity.username, nil }
+func (identity testIdentity) GetMSPID() (string, error) { return identity.mspID, nil }
+
+func (identity testIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	if attrName == common.RoleAttribute && len(identity.role) > 0 {
+		return identity.role, true, nil
+	}
+	return "", false, nil
+}
+
+func (identity testIdentity) AssertAttributeValue(attrName, attrValue string) error { return nil }
+
+// the username of the identities is
+// read from their organizational unit
+func (identity testIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: []string{identity.username}}}, nil
+}
+
+func newTestContext(stub shim.ChaincodeStubInterface, identity testIdentity) common.ITickenTxContext {
+	ctx := common.NewTransactionContext()
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+	return ctx
+}
+
+// putTestEvent stores "event" in its own transaction
+func putTestEvent(t *testing.T, stub *shimtest.MockStub, event *Event) {
+	stub.MockTransactionStart("setup")
+	defer stub.MockTransactionEnd("setup")
+
+	if err := new(Contract).putEvent(newTestContext(stub, adminIdentity), event); err != nil {
+		t.Fatalf("putEvent() error = %v", err)
+	}
+}
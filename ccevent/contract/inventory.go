@@ -0,0 +1,79 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+)
+
+// SetSoldTicketsBatch overwrites the ticket counters of the event with
+// "eventID": the ticket count of its sections, the tickets issued from
+// their allotments and the tickets sold during its sale phases. All the
+// counters are written at once, because each write of the event in a
+// transaction replaces the previous one. This is called by cc-ticket to
+// repair the counters of an event. The caller must be a platform admin
+//
+// Params
+// * - eventID     -> uuid format
+// * - corrections -> JSON object with the counters ({"sections": [{"session": "", "section": "vip", "sold_tickets": 10, "allotments": []}], "sale_phases": []})
+//
+// The return value can be:
+// * - the sections updated serialized in JSON format
+// * - error in case some counter can not be updated
+func (c *Contract) SetSoldTicketsBatch(ctx common.ITickenTxContext, eventID, corrections string) ([]*Section, error) {
+	if err := checkPlatformAdmin(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	var correctionsParsed common.InventoryCorrection
+	if err := json.Unmarshal([]byte(corrections), &correctionsParsed); err != nil {
+		return nil, ccErr("error parsing corrections: %v", err)
+	}
+
+	sections := make([]*Section, 0)
+	for _, correction := range correctionsParsed.Sections {
+		section, err := event.getSessionSection(correction.Session, correction.Section)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+
+		if correction.SoldTickets < 0 {
+			return nil, ccErr("invalid sold tickets %d - sold tickets can not be negative", correction.SoldTickets)
+		}
+
+		for _, allotmentCorrection := range correction.Allotments {
+			allotment := section.getAllotment(allotmentCorrection.Name)
+			if allotment == nil {
+				return nil, ccErr("allotment %s doest not exist in section %s", allotmentCorrection.Name, section.Name)
+			}
+			if allotmentCorrection.IssuedTickets < 0 {
+				return nil, ccErr("invalid issued tickets %d - issued tickets can not be negative", allotmentCorrection.IssuedTickets)
+			}
+			section.repairAllotment(allotment, allotmentCorrection.IssuedTickets)
+		}
+
+		section.SoldTickets = correction.SoldTickets
+		sections = append(sections, section)
+	}
+
+	for _, correction := range correctionsParsed.SalePhases {
+		phase := event.getSalePhase(correction.Name)
+		if phase == nil {
+			return nil, ccErr("sale phase %s does not exist", correction.Name)
+		}
+		if correction.SoldTickets < 0 {
+			return nil, ccErr("invalid sold tickets %d - sold tickets can not be negative", correction.SoldTickets)
+		}
+		phase.SoldTickets = correction.SoldTickets
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return sections, nil
+}
@@ -0,0 +1,152 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+	"time"
+)
+
+// eventSchema is the schema of the events stored in the ledger. When
+// the Event struct changes in a way that old records can not be read,
+// its version must be increased and an upgrade must be registered
+var eventSchema = common.NewSchema("event", 3).
+	RegisterUpgrade(0, upgradeEventV0).
+	RegisterUpgrade(1, upgradeEventV1).
+	RegisterUpgrade(2, upgradeEventV2)
+
+// Migrate rewrites with the current schema version the events stored
+// with an older one. At most "pageSize" events are read in each call,
+// starting from "bookmark", and the bookmark of the next page is
+// returned, so the whole ledger can be migrated in many transactions.
+// Events are also upgraded when they are read, so the migration can
+// run while the channel is live. The caller must be a platform admin
+//
+// Params
+// * - pageSize -> amount of events read in the transaction
+// * - bookmark -> bookmark returned by the previous page (empty for the first one)
+//
+// The return value can be:
+// * - the result of the page serialized in JSON format (empty bookmark on the last page)
+// * - error in case the caller is not an admin or an event can not be upgraded
+func (c *Contract) Migrate(ctx common.ITickenTxContext, pageSize, bookmark string) (*common.MigrationPage, error) {
+	if err := checkPlatformAdmin(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	pageSizeParsed, err := strconv.Atoi(pageSize)
+	if err != nil {
+		return nil, ccErr("error converting page size: %v", err)
+	}
+
+	// events are the only entity stored under simple
+	// keys, the rest of them use composite keys
+	page, err := eventSchema.Migrate(ctx, pageSizeParsed, bookmark)
+	if err != nil {
+		return nil, ccErr("failed to migrate events: %v", err)
+	}
+
+	return page, nil
+}
+
+// upgradeEventV0 upgrades the events stored before the envelope
+// existed. The lists added to the event after its creation are
+// missing or null in those records, and they are set as empty
+func upgradeEventV0(data json.RawMessage) (json.RawMessage, error) {
+	var event map[string]any
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+
+	setEmptyLists(event, "sections", "sale_phases", "delegates", "validators", "sessions")
+
+	setSectionsEmptyLists(event)
+	if sessions, ok := event["sessions"].([]any); ok {
+		for _, session := range sessions {
+			if session, ok := session.(map[string]any); ok {
+				setEmptyLists(session, "sections")
+				setSectionsEmptyLists(session)
+			}
+		}
+	}
+
+	return json.Marshal(event)
+}
+
+// upgradeEventV1 sets in UTC the dates of the event and its sessions.
+// Rich queries compare the dates as strings, so they must all be
+// stored with the same offset
+func upgradeEventV1(data json.RawMessage) (json.RawMessage, error) {
+	return updateEventDates(data, func(date time.Time) time.Time {
+		return date.UTC()
+	})
+}
+
+// upgradeEventV2 truncates to whole seconds the dates of the event
+// and its sessions, so they are all stored with the same width
+func upgradeEventV2(data json.RawMessage) (json.RawMessage, error) {
+	return updateEventDates(data, storedDate)
+}
+
+// updateEventDates replaces the date of the
+// event and its sessions with "update(date)"
+func updateEventDates(data json.RawMessage, update func(date time.Time) time.Time) (json.RawMessage, error) {
+	var event map[string]any
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+
+	if err := updateDate(event, "date", update); err != nil {
+		return nil, err
+	}
+
+	if sessions, ok := event["sessions"].([]any); ok {
+		for _, session := range sessions {
+			if session, ok := session.(map[string]any); ok {
+				if err := updateDate(session, "date", update); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return json.Marshal(event)
+}
+
+// setSectionsEmptyLists sets as empty the missing
+// lists of the sections of an event or a session
+func setSectionsEmptyLists(record map[string]any) {
+	sections, ok := record["sections"].([]any)
+	if !ok {
+		return
+	}
+
+	for _, section := range sections {
+		if section, ok := section.(map[string]any); ok {
+			setEmptyLists(section, "price_tiers", "allotments")
+		}
+	}
+}
+
+func setEmptyLists(record map[string]any, fields ...string) {
+	for _, field := range fields {
+		if record[field] == nil {
+			record[field] = make([]any, 0)
+		}
+	}
+}
+
+func updateDate(record map[string]any, field string, update func(date time.Time) time.Time) error {
+	date, ok := record[field].(string)
+	if !ok {
+		return nil
+	}
+
+	dateParsed, err := time.Parse(time.RFC3339Nano, date)
+	if err != nil {
+		return err
+	}
+
+	record[field] = update(dateParsed).Format(time.RFC3339Nano)
+	return nil
+}
@@ -0,0 +1,364 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"time"
+)
+
+const organizerIndex = "organizer~mspID~username"
+
+type OrganizerStatus string
+
+const (
+	// OrganizerStatusPending is the status of an organizer
+	// that is registered but not yet verified by the platform
+	OrganizerStatusPending OrganizerStatus = "pending"
+
+	// OrganizerStatusVerified is the status of an organizer
+	// that is allowed to create and publish events
+	OrganizerStatusVerified OrganizerStatus = "verified"
+
+	// OrganizerStatusSuspended is the status of an organizer
+	// that is not allowed to operate in the platform
+	OrganizerStatusSuspended OrganizerStatus = "suspended"
+)
+
+type Organizer struct {
+	// identity of the organizer, the same that
+	// is stored in the events that it creates
+	MSPID    string `json:"msp_id"`
+	Username string `json:"username"`
+
+	// profile data
+	DisplayName string `json:"display_name"`
+	LegalName   string `json:"legal_name"`
+	TaxID       string `json:"tax_id"`
+	Email       string `json:"email"`
+
+	Status OrganizerStatus `json:"status"`
+
+	// reason of the last status change
+	// and the moment when it happened
+	StatusReason string    `json:"status_reason"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// status of the organizer when it was suspended,
+	// so it goes back to it when it is reinstated
+	SuspendedFrom OrganizerStatus `json:"suspended_from,omitempty" metadata:",optional"`
+}
+
+// RegisterOrganizer registers the identity "mspID"/"username" as an
+// event organizer. The organizer is created with status "pending" and
+// it can not create events until it is verified. Only platform admins
+// can register organizers
+//
+// Params
+// * - mspID       -> MSP of the organizer identity
+// * - username    -> username of the organizer identity
+// * - displayName -> public name of the organizer
+// * - legalName   -> legal name of the organizer
+// * - taxID       -> tax identification of the organizer
+// * - email       -> contact email
+//
+// The return value can be:
+// * - the organizer registered serialized in JSON format
+// * - error in case the organizer already exists or the caller is not an admin
+func (c *Contract) RegisterOrganizer(ctx common.ITickenTxContext, mspID, username, displayName, legalName, taxID, email string) (*Organizer, error) {
+	if err := checkPlatformAdmin(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if len(mspID) == 0 || len(username) == 0 {
+		return nil, ccErr("organizer msp id and username are required")
+	}
+
+	existentOrganizer, _ := c.GetOrganizer(ctx, mspID, username)
+	if existentOrganizer != nil {
+		return nil, ccErr("organizer %s@%s already exists", username, mspID)
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	organizer := Organizer{
+		MSPID:       mspID,
+		Username:    username,
+		DisplayName: displayName,
+		LegalName:   legalName,
+		TaxID:       taxID,
+		Email:       email,
+		Status:      OrganizerStatusPending,
+		UpdatedAt:   now,
+	}
+
+	if err := putOrganizer(ctx, &organizer); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &organizer, nil
+}
+
+// UpdateOrganizerProfile replaces the profile data of the organizer.
+// Only platform admins can update organizers
+//
+// Params
+// * - mspID       -> MSP of the organizer identity
+// * - username    -> username of the organizer identity
+// * - displayName -> public name of the organizer
+// * - legalName   -> legal name of the organizer
+// * - taxID       -> tax identification of the organizer
+// * - email       -> contact email
+//
+// The return value can be:
+// * - the organizer updated serialized in JSON format
+// * - error in case the organizer is not found or the caller is not an admin
+func (c *Contract) UpdateOrganizerProfile(ctx common.ITickenTxContext, mspID, username, displayName, legalName, taxID, email string) (*Organizer, error) {
+	if err := checkPlatformAdmin(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	organizer, err := c.GetOrganizer(ctx, mspID, username)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	organizer.DisplayName = displayName
+	organizer.LegalName = legalName
+	organizer.TaxID = taxID
+	organizer.Email = email
+
+	if err := putOrganizer(ctx, organizer); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return organizer, nil
+}
+
+// VerifyOrganizer sets the organizer on status "verified", allowing
+// it to create and publish events. Only platform admins can verify
+// organizers, and suspended organizers must be reinstated instead
+//
+// Params
+// * - mspID    -> MSP of the organizer identity
+// * - username -> username of the organizer identity
+//
+// The return value can be:
+// * - the organizer updated serialized in JSON format
+// * - error in case the organizer cant transition to status "verified"
+func (c *Contract) VerifyOrganizer(ctx common.ITickenTxContext, mspID, username string) (*Organizer, error) {
+	return c.setOrganizerStatus(ctx, mspID, username, []OrganizerStatus{OrganizerStatusPending}, OrganizerStatusVerified, "")
+}
+
+// SuspendOrganizer sets the organizer on status "suspended". From this
+// moment, the organizer can not create or publish events, and the tickets
+// of all its events stop being sold. Pending organizers can be suspended
+// as well, so they can not be verified. Only platform admins can suspend
+// organizers
+//
+// Params
+// * - mspID    -> MSP of the organizer identity
+// * - username -> username of the organizer identity
+// * - reason   -> reason of the suspension
+//
+// The return value can be:
+// * - the organizer updated serialized in JSON format
+// * - error in case the organizer cant transition to status "suspended"
+func (c *Contract) SuspendOrganizer(ctx common.ITickenTxContext, mspID, username, reason string) (*Organizer, error) {
+	from := []OrganizerStatus{OrganizerStatusPending, OrganizerStatusVerified}
+	return c.setOrganizerStatus(ctx, mspID, username, from, OrganizerStatusSuspended, reason)
+}
+
+// ReinstateOrganizer sets a suspended organizer back on the status it had
+// when it was suspended ("pending" or "verified"). Only platform admins can
+// reinstate organizers
+//
+// Params
+// * - mspID    -> MSP of the organizer identity
+// * - username -> username of the organizer identity
+// * - reason   -> reason of the reinstatement
+//
+// The return value can be:
+// * - the organizer updated serialized in JSON format
+// * - error in case the organizer is not suspended
+func (c *Contract) ReinstateOrganizer(ctx common.ITickenTxContext, mspID, username, reason string) (*Organizer, error) {
+	organizer, err := c.GetOrganizer(ctx, mspID, username)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	// organizers suspended before the previous status
+	// was recorded could only be suspended if verified
+	to := OrganizerStatusVerified
+	if organizer.SuspendedFrom == OrganizerStatusPending {
+		to = OrganizerStatusPending
+	}
+
+	return c.setOrganizerStatus(ctx, mspID, username, []OrganizerStatus{OrganizerStatusSuspended}, to, reason)
+}
+
+// GetOrganizer returns the organizer registered with the
+// identity "mspID"/"username"
+//
+// Params
+// * - mspID    -> MSP of the organizer identity
+// * - username -> username of the organizer identity
+//
+// The return value can be:
+// * - error in case of the organizer is not found
+func (c *Contract) GetOrganizer(ctx common.ITickenTxContext, mspID, username string) (*Organizer, error) {
+	organizer, err := findOrganizer(ctx, mspID, username)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+	if organizer == nil {
+		return nil, ccErr("organizer %s@%s is not registered", username, mspID)
+	}
+
+	return organizer, nil
+}
+
+// findOrganizer returns the organizer with identity "mspID"/"username",
+// or nil if the identity is not registered as an organizer
+func findOrganizer(ctx common.ITickenTxContext, mspID, username string) (*Organizer, error) {
+	organizerKey, err := ctx.GetStub().CreateCompositeKey(organizerIndex, []string{mspID, username})
+	if err != nil {
+		return nil, ccErr("failed to create organizer key: %v", err)
+	}
+
+	organizerJSON, err := ctx.GetStub().GetState(organizerKey)
+	if err != nil {
+		return nil, ccErr("failed to read organizer: %v", err)
+	}
+	if organizerJSON == nil {
+		return nil, nil
+	}
+
+	var organizer Organizer
+	if err := json.Unmarshal(organizerJSON, &organizer); err != nil {
+		return nil, ccErr("failed to deserialize organizer: %v", err)
+	}
+
+	return &organizer, nil
+}
+
+func (c *Contract) setOrganizerStatus(ctx common.ITickenTxContext, mspID, username string, from []OrganizerStatus, to OrganizerStatus, reason string) (*Organizer, error) {
+	if err := checkPlatformAdmin(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	organizer, err := c.GetOrganizer(ctx, mspID, username)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if organizer.Status == to {
+		return nil, ccErr("organizer %s@%s already is on status %s", username, mspID, to)
+	}
+
+	if !containsOrganizerStatus(from, organizer.Status) {
+		return nil, ccErr("organizer cant go from %s to %s", organizer.Status, to)
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	organizer.SuspendedFrom = ""
+	if to == OrganizerStatusSuspended {
+		organizer.SuspendedFrom = organizer.Status
+	}
+
+	organizer.Status = to
+	organizer.StatusReason = reason
+	organizer.UpdatedAt = now
+
+	if err := putOrganizer(ctx, organizer); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return organizer, nil
+}
+
+func containsOrganizerStatus(statuses []OrganizerStatus, status OrganizerStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrganizerEnabled fails if the identity "mspID"/"username"
+// is not a verified organizer of the platform
+func (c *Contract) checkOrganizerEnabled(ctx common.ITickenTxContext, mspID, username string) error {
+	organizer, err := c.GetOrganizer(ctx, mspID, username)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if organizer.Status != OrganizerStatusVerified {
+		return ccErr("organizer %s@%s is on status %s", username, mspID, organizer.Status)
+	}
+
+	return nil
+}
+
+// checkOrganizerNotSuspended fails if the identity "mspID"/"username"
+// is a suspended organizer. Events created before the registry existed
+// have organizers that are not registered, and they are not blocked
+func (c *Contract) checkOrganizerNotSuspended(ctx common.ITickenTxContext, mspID, username string) error {
+	organizer, err := findOrganizer(ctx, mspID, username)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if organizer != nil && organizer.Status == OrganizerStatusSuspended {
+		return ccErr("organizer %s@%s is suspended", username, mspID)
+	}
+
+	return nil
+}
+
+func putOrganizer(ctx common.ITickenTxContext, organizer *Organizer) error {
+	organizerKey, err := ctx.GetStub().CreateCompositeKey(organizerIndex, []string{organizer.MSPID, organizer.Username})
+	if err != nil {
+		return ccErr("failed to create organizer key: %v", err)
+	}
+
+	organizerJSON, err := json.Marshal(organizer)
+	if err != nil {
+		return ccErr("failed to serialize organizer: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(organizerKey, organizerJSON); err != nil {
+		return ccErr("failed to update ledger: %v", err)
+	}
+
+	return nil
+}
+
+func checkPlatformAdmin(ctx common.ITickenTxContext) error {
+	isAdmin, err := ctx.IsPlatformAdmin()
+	if err != nil {
+		return ccErr("could not get context identity: %v", err)
+	}
+	if !isAdmin {
+		return ccErr("only platform admins can perform this operation")
+	}
+	return nil
+}
+
+func checkTicketService(ctx common.ITickenTxContext) error {
+	isTicketService, err := ctx.HasRole(common.RoleTicketService)
+	if err != nil {
+		return ccErr("could not get context identity: %v", err)
+	}
+	if !isTicketService {
+		return ccErr("only the ticket service can perform this operation")
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"math"
+	"strconv"
+	"time"
+)
+
+type PriceTier struct {
+	// amount of tickets sold at this price. Tiers
+	// are consumed in order, so the second tier
+	// starts once the first one is sold. Zero
+	// means that the tier has no limit
+	Tickets int     `json:"tickets"`
+	Price   float64 `json:"price"`
+
+	// optional moment from which the tier no
+	// longer applies (ex: early bird prices)
+	Until time.Time `json:"until"`
+}
+
+// TicketSale is the result of selling a ticket of a section,
+// including the price that was charged at the moment of the sale
+type TicketSale struct {
+	EventID   string  `json:"event_id"`
+	Session   string  `json:"session"`
+	Section   string  `json:"section"`
+	SalePhase string  `json:"sale_phase"`
+	Price     float64 `json:"price"`
+
+	// allotment of the section from which the ticket
+	// was issued, empty for the public sale tickets
+	Allotment string `json:"allotment"`
+
+	// offers made to waitlisted owners in the sale, so the
+	// caller can emit them when it is another chaincode
+	WaitlistOffers []*WaitlistOffer `json:"waitlist_offers"`
+}
+
+// AddPriceTier appends a price tier to the section "sectionName".
+// Tiers are applied in the order they were added: the first tier
+// prices the first "tickets" sold, the second one the following
+// "tickets" and so on. When no tier applies, the section ticket
+// price is charged. Tiers can only be added while the event is
+// in status "draft"
+//
+// Params
+// * - eventID     -> uuid format
+// * - sectionName -> unique name that identifies the section in the event
+// * - tickets     -> amount of tickets priced by the tier ("0" for no limit)
+// * - price       -> price of the tickets in this tier
+// * - until       -> RFC3339 format or empty if the tier is not time limited
+//
+// The return value can be:
+// * - the section updated serialized in JSON format
+// * - error in case some conditions to add the tier are not fulfilled
+func (c *Contract) AddPriceTier(ctx common.ITickenTxContext, eventID, sectionName, tickets, price, until string) (*Section, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionManageSections); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft {
+		return nil, ccErr("event is not in status draft")
+	}
+
+	section := event.getSection(sectionName)
+	if section == nil {
+		return nil, ccErr("section %s doest not exist in event %s", sectionName, eventID)
+	}
+
+	ticketsParsed, err := strconv.Atoi(tickets)
+	if err != nil {
+		return nil, ccErr("error converting tier tickets: %v", err)
+	}
+	if ticketsParsed < 0 {
+		return nil, ccErr("invalid tier tickets value %d - tickets can not be negative", ticketsParsed)
+	}
+
+	priceParsed, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return nil, ccErr("error converting tier price: %v", err)
+	}
+	if priceParsed < 0 {
+		return nil, ccErr("invalid tier price %f - price can not be negative", priceParsed)
+	}
+
+	var untilParsed time.Time
+	if len(until) > 0 {
+		untilParsed, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, ccErr("error parsing tier end: %v", err)
+		}
+	}
+
+	for _, sectionCopy := range event.getSectionCopies(sectionName) {
+		sectionCopy.PriceTiers = append(sectionCopy.PriceTiers, &PriceTier{
+			Tickets: ticketsParsed,
+			Price:   math.Round(priceParsed*100) / 100,
+			Until:   untilParsed.UTC(),
+		})
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return section, nil
+}
+
+// ClearPriceTiers removes all the price tiers of the section
+// "sectionName", so its tickets are sold at the section ticket
+// price. Tiers can only be removed while the event is in
+// status "draft"
+//
+// Params
+// * - eventID     -> uuid format
+// * - sectionName -> unique name that identifies the section in the event
+//
+// The return value can be:
+// * - error in case the section is not found or the event is not in draft
+func (c *Contract) ClearPriceTiers(ctx common.ITickenTxContext, eventID, sectionName string) error {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionManageSections); err != nil {
+		return err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft {
+		return ccErr("event is not in status draft")
+	}
+
+	section := event.getSection(sectionName)
+	if section == nil {
+		return ccErr("section %s doest not exist in event %s", sectionName, eventID)
+	}
+
+	for _, sectionCopy := range event.getSectionCopies(section.Name) {
+		sectionCopy.PriceTiers = make([]*PriceTier, 0)
+	}
+
+	return c.putEvent(ctx, event)
+}
+
+// GetSectionPrice returns the price that would be charged
+// for the next ticket of the section at the transaction time
+//
+// Params
+// * - eventID     -> uuid format
+// * - sessionID   -> uuid format (must be empty for events without sessions)
+// * - sectionName -> unique name that identifies the section in the event
+//
+// The return value can be:
+// * - the current price of the section
+// * - error in case the event or the section are not found
+func (c *Contract) GetSectionPrice(ctx common.ITickenTxContext, eventID, sessionID, sectionName string) (float64, error) {
+	_, section, err := c.getEventSection(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return 0, err // this error is already formatted
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return 0, ccErr("failed to get transaction time: %v", err)
+	}
+
+	return section.currentPrice(now), nil
+}
+
+// currentPrice returns the price of the next ticket to be
+// sold. The tiers are walked accumulating their tickets until
+// reaching the first one that still has tickets and has not
+// expired at "now"
+func (section *Section) currentPrice(now time.Time) float64 {
+	tierEnd := 0
+	for _, tier := range section.PriceTiers {
+		if tier.Tickets == 0 {
+			tierEnd = math.MaxInt32
+		} else {
+			tierEnd += tier.Tickets
+		}
+
+		expired := !tier.Until.IsZero() && !now.Before(tier.Until)
+		if section.publicSoldTickets() < tierEnd && !expired {
+			return tier.Price
+		}
+	}
+
+	return section.TicketPrice
+}
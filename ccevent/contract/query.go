@@ -0,0 +1,101 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// EventPage is a page of the events found by QueryEvents
+type EventPage struct {
+	Events []*Event `json:"events"`
+
+	// bookmark used to request the next page,
+	// empty when there are no more events
+	Bookmark string `json:"bookmark"`
+}
+
+// QueryEvents returns the events that match all the filters received,
+// ignoring the empty ones. The query runs on the CouchDB state database
+// using the indexes packaged in META-INF, and it fails with a clear
+// error if the peer runs LevelDB. Events are compared by their date
+// in UTC, and the range is inclusive on both ends. Event dates are
+// stored in whole seconds, so the bounds are rounded to the seconds
+// that include the same events
+//
+// Params
+// * - name     -> text contained in the name of the event (case insensitive)
+// * - status   -> status of the event
+// * - from     -> RFC3339 format (events on this date or later)
+// * - to       -> RFC3339 format (events on this date or earlier)
+// * - pageSize -> amount of events returned
+// * - bookmark -> bookmark returned by the previous page (empty for the first one)
+//
+// The return value can be:
+// * - the page of events serialized in JSON format (empty bookmark on the last page)
+// * - error in case some filter is invalid or the query can not run
+func (c *Contract) QueryEvents(ctx common.ITickenTxContext, name, status, from, to, pageSize, bookmark string) (*EventPage, error) {
+	selector := make(map[string]any)
+
+	if len(name) > 0 {
+		selector["data.name"] = map[string]any{"$regex": "(?i)" + regexp.QuoteMeta(name)}
+	}
+
+	if len(status) > 0 {
+		selector["data.status"] = status
+	}
+
+	dateRange := make(map[string]any)
+	if len(from) > 0 {
+		fromParsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, ccErr("error parsing from date: %v", err)
+		}
+		// first whole second not before "from"
+		dateRange["$gte"] = storedDate(fromParsed.Add(time.Second - time.Nanosecond)).Format(time.RFC3339)
+	}
+	if len(to) > 0 {
+		toParsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, ccErr("error parsing to date: %v", err)
+		}
+		dateRange["$lte"] = storedDate(toParsed).Format(time.RFC3339)
+	}
+	if len(dateRange) > 0 {
+		selector["data.date"] = dateRange
+	}
+
+	pageSizeParsed, err := strconv.Atoi(pageSize)
+	if err != nil {
+		return nil, ccErr("error converting page size: %v", err)
+	}
+
+	page, err := eventSchema.Query(ctx, selector, pageSizeParsed, bookmark)
+	if err != nil {
+		return nil, ccErr("failed to query events: %v", err)
+	}
+
+	eventPage := EventPage{
+		Events:   make([]*Event, 0),
+		Bookmark: page.Bookmark,
+	}
+
+	for _, record := range page.Records {
+		var event Event
+		if _, err := eventSchema.Unmarshal(record, &event); err != nil {
+			return nil, ccErr("failed to deserialize event: %v", err)
+		}
+		eventPage.Events = append(eventPage.Events, &event)
+	}
+
+	return &eventPage, nil
+}
+
+// storedDate returns "date" in UTC truncated to whole seconds, as the
+// dates of the events and sessions are stored. Rich queries compare
+// the dates as strings, and the fractional seconds would make them
+// have different widths
+func storedDate(date time.Time) time.Time {
+	return date.UTC().Truncate(time.Second)
+}
@@ -0,0 +1,75 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"math"
+	"strconv"
+)
+
+// RefundPolicy defines if and until when the owners of the
+// tickets of an event can ask for a refund, and how much of
+// the price paid is returned
+type RefundPolicy struct {
+	// refunds can be requested until this amount
+	// of hours before the event date
+	DeadlineHours int `json:"deadline_hours"`
+
+	// percentage of the ticket price that is refunded.
+	// Zero means that the event does not allow refunds
+	Percentage float64 `json:"percentage"`
+}
+
+// SetRefundPolicy sets the refund policy of the event. The policy can
+// only be changed while the event is in status "draft", so the buyers
+// know the conditions before purchasing. The caller must be the
+// organizer or a delegate with permission "edit_draft"
+//
+// Params
+// * - eventID       -> uuid format
+// * - deadlineHours -> hours before the event date until refunds can be requested
+// * - percentage    -> percentage of the price refunded (0 - 100, "0" disables refunds)
+//
+// The return value can be:
+// * - the policy set serialized in JSON format
+// * - error in case some conditions to set the policy are not fulfilled
+func (c *Contract) SetRefundPolicy(ctx common.ITickenTxContext, eventID, deadlineHours, percentage string) (*RefundPolicy, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionEditDraft); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft {
+		return nil, ccErr("event is not in status draft")
+	}
+
+	deadlineHoursParsed, err := strconv.Atoi(deadlineHours)
+	if err != nil {
+		return nil, ccErr("error converting deadline hours: %v", err)
+	}
+	if deadlineHoursParsed < 0 {
+		return nil, ccErr("invalid deadline hours %d - deadline can not be negative", deadlineHoursParsed)
+	}
+
+	percentageParsed, err := strconv.ParseFloat(percentage, 64)
+	if err != nil {
+		return nil, ccErr("error converting percentage: %v", err)
+	}
+	if percentageParsed < 0 || percentageParsed > 100 {
+		return nil, ccErr("invalid percentage %f - percentage must be between 0 and 100", percentageParsed)
+	}
+
+	event.RefundPolicy = &RefundPolicy{
+		DeadlineHours: deadlineHoursParsed,
+		Percentage:    math.Round(percentageParsed*100) / 100,
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return event.RefundPolicy, nil
+}
@@ -0,0 +1,77 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+)
+
+// ReleaseTicket decreases in one the ticket count on the section with
+// "sectionName" of the event with "eventID", returning the seat to the
+// inventory. For events with sessions, the seat is returned to the
+// session with "sessionID". If the ticket was issued from an allotment,
+// the seat goes back to it, unless the allotment was removed. This is
+// called by cc-ticket when a ticket is voided. The counters of the sale
+// phases are not modified, because the phase in which the ticket was
+// sold is unknown. If the section has a waitlist, the seat is offered
+// to its next owner. The transaction must be submitted to cc-ticket,
+// so seats are only released when a ticket is voided, and the caller
+// must be the event organizer or a platform admin
+//
+// Params
+// * - eventID       -> uuid format
+// * - sessionID     -> uuid format (must be empty for events without sessions)
+// * - sectionName   -> unique name that identifies the section in the event
+// * - allotmentName -> allotment from which the ticket was issued (can be empty)
+//
+// The return value can be:
+// * - the offers made to waitlisted owners (possibly empty)
+// * - error in case the seat can not be released
+func (c *Contract) ReleaseTicket(ctx common.ITickenTxContext, eventID, sessionID, sectionName, allotmentName string) ([]*WaitlistOffer, error) {
+	caller, err := common.TopLevelChaincode(ctx.GetStub())
+	if err != nil {
+		return nil, ccErr("failed to read the invoked chaincode: %v", err)
+	}
+	if caller != ccTicketName {
+		return nil, ccErr("tickets can only be released by %s", ccTicketName)
+	}
+
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkEventOwnerOrAdmin(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusOnSale && event.Status != EventStatusSalesClosed && event.Status != EventStatusRunning {
+		return nil, ccErr("tickets of event on status %s can not be released", event.Status)
+	}
+
+	section, err := event.getSessionSection(sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if section.SoldTickets == 0 {
+		return nil, ccErr("section %s has no sold tickets", sectionName)
+	}
+
+	if len(allotmentName) > 0 {
+		allotment := section.getAllotment(allotmentName)
+		if allotment == nil {
+			return nil, ccErr("allotment %s doest not exist in section %s", allotmentName, sectionName)
+		}
+		if allotment.IssuedTickets == 0 {
+			return nil, ccErr("allotment %s has no issued tickets", allotmentName)
+		}
+		section.releaseAllotmentTicket(allotment)
+	}
+
+	section.SoldTickets -= 1
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return offerReleasedSeats(ctx, event, section)
+}
@@ -0,0 +1,90 @@
+package contract
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"strings"
+	"testing"
+)
+
+// proposalStub is a mock stub whose transactions
+// were submitted by the client to "chaincode"
+type proposalStub struct {
+	*shimtest.MockStub
+	chaincode string
+}
+
+func (stub *proposalStub) GetSignedProposal() (*pb.SignedProposal, error) {
+	invocationSpec, err := proto.Marshal(&pb.ChaincodeInvocationSpec{
+		ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeId: &pb.ChaincodeID{Name: stub.chaincode}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := proto.Marshal(&pb.ChaincodeProposalPayload{Input: invocationSpec})
+	if err != nil {
+		return nil, err
+	}
+
+	proposal, err := proto.Marshal(&pb.Proposal{Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.SignedProposal{ProposalBytes: proposal}, nil
+}
+
+func TestReleaseTicketAuthorization(t *testing.T) {
+	const eventID = "22222222-2222-2222-2222-222222222222"
+
+	tests := []struct {
+		name      string
+		chaincode string
+		identity  testIdentity
+		wantErr   string
+	}{
+		{name: "organizer through cc-ticket", chaincode: ccTicketName, identity: organizerIdentity},
+		{name: "platform admin through cc-ticket", chaincode: ccTicketName, identity: adminIdentity},
+		{name: "organizer through cc-event", chaincode: Name, identity: organizerIdentity, wantErr: "can only be released by " + ccTicketName},
+		{name: "other identity through cc-ticket", chaincode: ccTicketName, identity: testIdentity{mspID: "Org2MSP", username: "org2"}, wantErr: "only the organizer"},
+		{name: "ticket service through cc-ticket", chaincode: ccTicketName, identity: ticketServiceIdentity, wantErr: "only the organizer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := shimtest.NewMockStub(Name, nil)
+			putTestEvent(t, stub, &Event{
+				EventID:           eventID,
+				Status:            EventStatusOnSale,
+				MSPID:             organizerIdentity.mspID,
+				OrganizerUsername: organizerIdentity.username,
+				Sections:          []*Section{{EventID: eventID, Name: "vip", TotalTickets: 10, SoldTickets: 1}},
+			})
+
+			stub.MockTransactionStart("release")
+			ctx := newTestContext(&proposalStub{MockStub: stub, chaincode: tt.chaincode}, tt.identity)
+			_, err := new(Contract).ReleaseTicket(ctx, eventID, "", "vip", "")
+			stub.MockTransactionEnd("release")
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReleaseTicket() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReleaseTicket() error = %v", err)
+			}
+
+			event, err := new(Contract).GetEvent(newTestContext(stub, tt.identity), eventID)
+			if err != nil {
+				t.Fatalf("GetEvent() error = %v", err)
+			}
+			if soldTickets := event.Sections[0].SoldTickets; soldTickets != 0 {
+				t.Errorf("sold tickets = %d, want 0", soldTickets)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package contract
+
+import (
+	"encoding/hex"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+	"time"
+)
+
+type SalePhase struct {
+	Name string `json:"name"`
+
+	// names of the sections released in this
+	// phase. Empty means that all sections
+	// of the event are included
+	Sections []string `json:"sections"`
+
+	// window in which the phase is open. A zero
+	// EndsAt means that the phase has no end
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+
+	// max amount of tickets that can be sold
+	// during this phase. Zero means no limit
+	// other than the sections capacity
+	Quota       int `json:"quota"`
+	SoldTickets int `json:"sold_tickets"`
+
+	// hex encoded Merkle root of the owner IDs
+	// allowed to buy during this phase. Empty
+	// means that the phase is open to everyone
+	AllowlistRoot string `json:"allowlist_root"`
+}
+
+// AddSalePhase adds a sale phase to the event. Once an event has
+// at least one phase, tickets can only be sold for the sections
+// included in a phase that is open at the transaction time.
+// Phases can only be added while the event is in status "draft"
+//
+// Params
+// * - eventID  -> uuid format
+// * - name     -> phase name (must be unique in the event)
+// * - startsAt -> RFC3339 format (2006-01-02T15:04:05Z07:00)
+// * - endsAt   -> RFC3339 format or empty for a phase without end
+// * - quota    -> max tickets sold during the phase ("0" for no limit)
+// * - sections -> names of the sections released (empty for all)
+//
+// The return value can be:
+// * - the phase added serialized in JSON format
+// * - error in case some conditions to add the phase are not fulfilled
+func (c *Contract) AddSalePhase(ctx common.ITickenTxContext, eventID, name, startsAt, endsAt, quota string, sections []string) (*SalePhase, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionEditDraft); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft {
+		return nil, ccErr("event is not in status draft")
+	}
+
+	if event.getSalePhase(name) != nil {
+		return nil, ccErr("sale phase with name %s already exists", name)
+	}
+
+	startsAtParsed, err := time.Parse(time.RFC3339, startsAt)
+	if err != nil {
+		return nil, ccErr("error parsing phase start: %v", err)
+	}
+
+	var endsAtParsed time.Time
+	if len(endsAt) > 0 {
+		endsAtParsed, err = time.Parse(time.RFC3339, endsAt)
+		if err != nil {
+			return nil, ccErr("error parsing phase end: %v", err)
+		}
+		if !endsAtParsed.After(startsAtParsed) {
+			return nil, ccErr("phase end must be after its start")
+		}
+	}
+
+	quotaParsed, err := strconv.Atoi(quota)
+	if err != nil {
+		return nil, ccErr("error converting quota: %v", err)
+	}
+	if quotaParsed < 0 {
+		return nil, ccErr("invalid quota value %d - quota can not be negative", quotaParsed)
+	}
+
+	for _, sectionName := range sections {
+		if event.getSection(sectionName) == nil {
+			return nil, ccErr("section %s doest not exist in event %s", sectionName, eventID)
+		}
+	}
+
+	if sections == nil {
+		sections = make([]string, 0)
+	}
+
+	newPhase := SalePhase{
+		Name:        name,
+		Sections:    sections,
+		StartsAt:    startsAtParsed.UTC(),
+		EndsAt:      endsAtParsed.UTC(),
+		Quota:       quotaParsed,
+		SoldTickets: 0,
+	}
+
+	event.SalePhases = append(event.SalePhases, &newPhase)
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &newPhase, nil
+}
+
+// RemoveSalePhase removes the sale phase with name "name" from
+// the event. Phases can only be removed while the event is
+// in status "draft"
+//
+// Params
+// * - eventID -> uuid format
+// * - name    -> phase name
+//
+// The return value can be:
+// * - error in case the phase is not found or the event is not in draft
+func (c *Contract) RemoveSalePhase(ctx common.ITickenTxContext, eventID, name string) error {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionEditDraft); err != nil {
+		return err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft {
+		return ccErr("event is not in status draft")
+	}
+
+	remainingPhases := make([]*SalePhase, 0)
+	for _, phase := range event.SalePhases {
+		if phase.Name != name {
+			remainingPhases = append(remainingPhases, phase)
+		}
+	}
+
+	if len(remainingPhases) == len(event.SalePhases) {
+		return ccErr("sale phase %s does not exist in event %s", name, eventID)
+	}
+
+	event.SalePhases = remainingPhases
+
+	return c.putEvent(ctx, event)
+}
+
+// GetActiveSalePhases returns the sale phases of the event that
+// are open at the transaction time. Phases are only considered
+// active while the event is in status "on_sale"
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the list of active phases (possibly empty)
+// * - error in case of the event is not found
+func (c *Contract) GetActiveSalePhases(ctx common.ITickenTxContext, eventID string) ([]*SalePhase, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	activePhases := make([]*SalePhase, 0)
+	if event.Status != EventStatusOnSale {
+		return activePhases, nil
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	for _, phase := range event.SalePhases {
+		if phase.isOpen(now) {
+			activePhases = append(activePhases, phase)
+		}
+	}
+
+	return activePhases, nil
+}
+
+// SetSalePhaseAllowlist restricts the sale phase "phaseName" to the
+// owners included in the Merkle tree with root "merkleRoot". Only the
+// root is stored in the ledger, so the allowlist can be arbitrarily
+// large. Buyers must provide the proof of inclusion of their owner ID
+// when the ticket is issued. The allowlist can only be changed while
+// the event is in status "draft"
+//
+// Params
+// * - eventID    -> uuid format
+// * - phaseName  -> phase name
+// * - merkleRoot -> hex encoded sha256 hash or empty to remove the allowlist
+//
+// The return value can be:
+// * - the updated phase serialized in JSON format
+// * - error in case the phase is not found or the root is invalid
+func (c *Contract) SetSalePhaseAllowlist(ctx common.ITickenTxContext, eventID, phaseName, merkleRoot string) (*SalePhase, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionEditDraft); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft {
+		return nil, ccErr("event is not in status draft")
+	}
+
+	phase := event.getSalePhase(phaseName)
+	if phase == nil {
+		return nil, ccErr("sale phase %s does not exist in event %s", phaseName, eventID)
+	}
+
+	phase.AllowlistRoot = ""
+	if len(merkleRoot) > 0 {
+		root, err := common.ParseMerkleHash(merkleRoot)
+		if err != nil {
+			return nil, ccErr("invalid merkle root: %v", err)
+		}
+		phase.AllowlistRoot = hex.EncodeToString(root)
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return phase, nil
+}
+
+// GetSectionAllowlistRoot returns the allowlist Merkle root of the
+// sale phase that will be used to sell a ticket of the section
+// "sectionName" at the transaction time. This is used by cc-ticket
+// to verify the buyer before selling the ticket
+//
+// Params
+// * - eventID     -> uuid format
+// * - sectionName -> unique name that identifies the section in the event
+//
+// The return value can be:
+// * - the hex encoded root or empty if the sale is not restricted
+// * - error in case the event is not found or there is no phase open
+func (c *Contract) GetSectionAllowlistRoot(ctx common.ITickenTxContext, eventID, sectionName string) (string, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return "", err // this error is already formatted
+	}
+
+	if len(event.SalePhases) == 0 {
+		return "", nil
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return "", ccErr("failed to get transaction time: %v", err)
+	}
+
+	phase := event.getSalePhaseFor(sectionName, now)
+	if phase == nil {
+		return "", ccErr("there is no sale phase open for section %s", sectionName)
+	}
+
+	return phase.AllowlistRoot, nil
+}
+
+func (event *Event) getSalePhase(name string) *SalePhase {
+	for _, phase := range event.SalePhases {
+		if phase.Name == name {
+			return phase
+		}
+	}
+	return nil
+}
+
+// getSalePhaseFor returns the first phase open at "now" that
+// includes the section and still has quota to sell a ticket
+func (event *Event) getSalePhaseFor(sectionName string, now time.Time) *SalePhase {
+	for _, phase := range event.SalePhases {
+		if phase.isOpen(now) && phase.includes(sectionName) && phase.hasQuota() {
+			return phase
+		}
+	}
+	return nil
+}
+
+func (phase *SalePhase) isOpen(now time.Time) bool {
+	return common.WithinWindow(now, phase.StartsAt, phase.EndsAt)
+}
+
+func (phase *SalePhase) includes(sectionName string) bool {
+	if len(phase.Sections) == 0 {
+		return true
+	}
+	for _, name := range phase.Sections {
+		if name == sectionName {
+			return true
+		}
+	}
+	return false
+}
+
+func (phase *SalePhase) hasQuota() bool {
+	return phase.Quota == 0 || phase.SoldTickets < phase.Quota
+}
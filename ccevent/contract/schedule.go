@@ -0,0 +1,186 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+	"time"
+)
+
+// Schedule holds the times at which the status of an event
+// changes. Once they pass, anyone can advance the event with
+// the transaction Advance
+type Schedule struct {
+	// the sales are stopped this amount of
+	// hours before the doors are opened
+	SalesCloseHours int `json:"sales_close_hours"`
+
+	// the event goes to status "running"
+	DoorsOpenAt time.Time `json:"doors_open_at"`
+
+	// the event goes to status "finished"
+	EndsAt time.Time `json:"ends_at"`
+}
+
+// SetSchedule sets the times used to advance the status of the event.
+// The sales are stopped "salesCloseHours" before "doorsOpenAt", the
+// event starts at "doorsOpenAt" and finishes at "endsAt". The schedule
+// can only be changed while the event is in status "draft", and events
+// with sessions can not have one, because their sessions are started
+// and finished one by one. The caller must be the organizer or a
+// delegate with permission "edit_draft"
+//
+// Params
+// * - eventID         -> uuid format
+// * - doorsOpenAt     -> RFC3339 format
+// * - endsAt          -> RFC3339 format (must be after doorsOpenAt)
+// * - salesCloseHours -> hours before doorsOpenAt when the sales stop ("0" to sell until the doors open)
+//
+// The return value can be:
+// * - the schedule set serialized in JSON format
+// * - error in case some conditions to set the schedule are not fulfilled
+func (c *Contract) SetSchedule(ctx common.ITickenTxContext, eventID, doorsOpenAt, endsAt, salesCloseHours string) (*Schedule, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionEditDraft); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft {
+		return nil, ccErr("event is not in status draft")
+	}
+
+	if len(event.Sessions) > 0 {
+		return nil, ccErr("events with sessions can not have a schedule")
+	}
+
+	doorsOpenAtParsed, err := time.Parse(time.RFC3339, doorsOpenAt)
+	if err != nil {
+		return nil, ccErr("error parsing doors open time: %v", err)
+	}
+
+	endsAtParsed, err := time.Parse(time.RFC3339, endsAt)
+	if err != nil {
+		return nil, ccErr("error parsing end time: %v", err)
+	}
+
+	if !doorsOpenAtParsed.Before(endsAtParsed) {
+		return nil, ccErr("the event must end after the doors open")
+	}
+
+	salesCloseHoursParsed, err := strconv.Atoi(salesCloseHours)
+	if err != nil {
+		return nil, ccErr("error converting sales close hours: %v", err)
+	}
+	if salesCloseHoursParsed < 0 {
+		return nil, ccErr("invalid sales close hours %d - hours can not be negative", salesCloseHoursParsed)
+	}
+
+	event.Schedule = &Schedule{
+		SalesCloseHours: salesCloseHoursParsed,
+		DoorsOpenAt:     doorsOpenAtParsed.UTC(),
+		EndsAt:          endsAtParsed.UTC(),
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return event.Schedule, nil
+}
+
+// Advance moves the event to the status implied by its schedule at the
+// transaction time, firing every transition whose time has passed. Only
+// events on sale or later are advanced, and events without schedule or
+// with sessions are left untouched. As the transitions only depend on the schedule set by
+// the organizer, anyone can call this transaction, so a scheduler
+// service can call it periodically for every event
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the event with its current status serialized in JSON format
+// * - error in case the event can not be read or updated
+func (c *Contract) Advance(ctx common.ITickenTxContext, eventID string) (*Event, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Schedule == nil || len(event.Sessions) > 0 {
+		return event, nil
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	stateMachine := c.eventStateMachine()
+
+	advanced := false
+	for _, transition := range event.Schedule.dueTransitions(now) {
+		if !stateMachine.CanFire(transition, event) {
+			continue
+		}
+
+		if err := stateMachine.Fire(ctx, transition, event); err != nil {
+			return nil, err // this error is already formatted
+		}
+		advanced = true
+	}
+
+	if !advanced {
+		return event, nil
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return event, nil
+}
+
+// dueTransitions returns the scheduled transitions whose
+// time has passed at "now", in the order they must be fired
+func (schedule *Schedule) dueTransitions(now time.Time) []string {
+	transitions := make([]string, 0)
+	if !now.Before(schedule.salesCloseAt()) {
+		transitions = append(transitions, transitionCloseSales)
+	}
+	if !now.Before(schedule.DoorsOpenAt) {
+		transitions = append(transitions, transitionOpenDoors)
+	}
+	if !now.Before(schedule.EndsAt) {
+		transitions = append(transitions, transitionEnd)
+	}
+	return transitions
+}
+
+func (schedule *Schedule) salesCloseAt() time.Time {
+	return schedule.DoorsOpenAt.Add(-time.Duration(schedule.SalesCloseHours) * time.Hour)
+}
+
+// scheduleReached returns a guard that fails if the
+// time returned by "scheduledAt" has not passed yet
+// or if the event has no schedule
+func scheduleReached(scheduledAt func(schedule *Schedule) time.Time) common.Guard[*Event] {
+	return func(ctx common.ITickenTxContext, event *Event) error {
+		if event.Schedule == nil {
+			return ccErr("event %s has no schedule", event.EventID)
+		}
+
+		passed, err := common.HasPassed(ctx, scheduledAt(event.Schedule))
+		if err != nil {
+			return ccErr("failed to get transaction time: %v", err)
+		}
+		if !passed {
+			return ccErr("the schedule of event %s was not reached yet", event.EventID)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,237 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"math"
+)
+
+const seriesIndex = "series~seriesID"
+
+// Series groups events that are sold together, such as the
+// days of a festival or the matches of a season. A pass of the
+// series holds a seat in the same section of every event
+type Series struct {
+	SeriesID string   `json:"series_id"`
+	Name     string   `json:"name"`
+	EventIDs []string `json:"event_ids"`
+
+	// identity of the organizer, that must be
+	// the organizer of all the events
+	MSPID             string `json:"msp_id"`
+	OrganizerUsername string `json:"organizer_username"`
+}
+
+// PassSale is the result of selling a pass of a series,
+// with the sale of the ticket of every event of the series
+type PassSale struct {
+	SeriesID string        `json:"series_id"`
+	Section  string        `json:"section"`
+	Price    float64       `json:"price"`
+	Sales    []*TicketSale `json:"sales"`
+
+	// offers made to waitlisted owners in the sale, so the
+	// caller can emit them when it is another chaincode
+	WaitlistOffers []*WaitlistOffer `json:"waitlist_offers"`
+}
+
+// CreateSeries creates an empty series of events. The
+// caller must be a verified organizer
+//
+// Params
+// * - seriesID -> uuid format
+// * - name     -> name of the series (ex: the festival name)
+//
+// The return value can be:
+// * - the series created serialized in JSON format
+// * - error in case the series can not be created
+func (c *Contract) CreateSeries(ctx common.ITickenTxContext, seriesID, name string) (*Series, error) {
+	seriesIDParsed, err := uuid.Parse(seriesID)
+	if err != nil {
+		return nil, ccErr("error parsing series id: %v", err)
+	}
+
+	existentSeries, _ := c.GetSeries(ctx, seriesIDParsed.String())
+	if existentSeries != nil {
+		return nil, ccErr("series with ID %s already exists", seriesID)
+	}
+
+	mspID, orgUsername, err := ctx.GetContextIdentity()
+	if err != nil {
+		return nil, ccErr("could not get context identity: %v", err)
+	}
+
+	if err := c.checkOrganizerEnabled(ctx, mspID, orgUsername); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	series := Series{
+		SeriesID:          seriesIDParsed.String(),
+		Name:              name,
+		EventIDs:          make([]string, 0),
+		MSPID:             mspID,
+		OrganizerUsername: orgUsername,
+	}
+
+	if err := putSeries(ctx, &series); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &series, nil
+}
+
+// AddEventToSeries adds the event with ID "eventID" to the series
+// with ID "seriesID". An event can only belong to one series, and it
+// can only be added while it is in status "draft". The caller must be
+// the organizer of both the series and the event
+//
+// Params
+// * - seriesID -> uuid format
+// * - eventID  -> uuid format
+//
+// The return value can be:
+// * - the series updated serialized in JSON format
+// * - error in case the event can not be added
+func (c *Contract) AddEventToSeries(ctx common.ITickenTxContext, seriesID, eventID string) (*Series, error) {
+	series, err := c.GetSeries(ctx, seriesID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return nil, ccErr("could not get context identity: %v", err)
+	}
+
+	if series.MSPID != mspID || series.OrganizerUsername != username {
+		return nil, ccErr("only the organizer of the series %s can perform this operation", seriesID)
+	}
+
+	if err := checkEventOwner(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft {
+		return nil, ccErr("event is not in status draft")
+	}
+
+	if len(event.SeriesID) > 0 {
+		return nil, ccErr("event %s already belongs to series %s", eventID, event.SeriesID)
+	}
+
+	event.SeriesID = series.SeriesID
+	series.EventIDs = append(series.EventIDs, event.EventID)
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := putSeries(ctx, series); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return series, nil
+}
+
+// GetSeries returns the series with ID "seriesID"
+//
+// Params
+// * - seriesID -> uuid format
+//
+// The return value can be:
+// * - the series serialized in JSON format
+// * - error in case of the series is not found
+func (c *Contract) GetSeries(ctx common.ITickenTxContext, seriesID string) (*Series, error) {
+	seriesKey, err := ctx.GetStub().CreateCompositeKey(seriesIndex, []string{seriesID})
+	if err != nil {
+		return nil, ccErr("failed to create series key: %v", err)
+	}
+
+	seriesJSON, err := ctx.GetStub().GetState(seriesKey)
+	if err != nil {
+		return nil, ccErr("failed to read series: %v", err)
+	}
+	if seriesJSON == nil {
+		return nil, ccErr("series %s does not exist", seriesID)
+	}
+
+	var series Series
+	if err := json.Unmarshal(seriesJSON, &series); err != nil {
+		return nil, ccErr("failed to deserialize series: %v", err)
+	}
+
+	return &series, nil
+}
+
+// SellPass sells a ticket of the section "sectionName" in every event of
+// the series with ID "seriesID" in the same transaction, so the pass is
+// only sold if all the events have a seat for it. Each ticket follows the
+// rules of SellTicket, and the price of the pass is the sum of them.
+// Passes can not be sold for series of events with sessions
+//
+// Params
+// * - seriesID    -> uuid format
+// * - sectionName -> name of the section in every event of the series
+// * - ownerID     -> uuid format (owner that buys the pass)
+//
+// The return value can be:
+// * - the sale of the pass serialized in JSON format
+// * - error in case some event can not sell the ticket
+func (c *Contract) SellPass(ctx common.ITickenTxContext, seriesID, sectionName, ownerID string) (*PassSale, error) {
+	series, err := c.GetSeries(ctx, seriesID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if len(series.EventIDs) == 0 {
+		return nil, ccErr("series %s has no events", seriesID)
+	}
+
+	passSale := PassSale{
+		SeriesID:       series.SeriesID,
+		Section:        sectionName,
+		Sales:          make([]*TicketSale, 0),
+		WaitlistOffers: make([]*WaitlistOffer, 0),
+	}
+
+	for _, eventID := range series.EventIDs {
+		sale, err := c.SellTicket(ctx, eventID, "", sectionName, ownerID)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+
+		passSale.Price += sale.Price
+		passSale.Sales = append(passSale.Sales, sale)
+		passSale.WaitlistOffers = append(passSale.WaitlistOffers, sale.WaitlistOffers...)
+	}
+
+	// avoid floating point noise
+	// from the accumulated sum
+	passSale.Price = math.Round(passSale.Price*100) / 100
+
+	return &passSale, nil
+}
+
+func putSeries(ctx common.ITickenTxContext, series *Series) error {
+	seriesKey, err := ctx.GetStub().CreateCompositeKey(seriesIndex, []string{series.SeriesID})
+	if err != nil {
+		return ccErr("failed to create series key: %v", err)
+	}
+
+	seriesJSON, err := json.Marshal(series)
+	if err != nil {
+		return ccErr("failed to serialize series: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(seriesKey, seriesJSON); err != nil {
+		return ccErr("failed to update ledger: %v", err)
+	}
+
+	return nil
+}
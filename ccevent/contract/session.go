@@ -0,0 +1,250 @@
+package contract
+
+import (
+	"github.com/google/uuid"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"time"
+)
+
+// Session is a performance of an event on its own date, such
+// as each function of a theatre run. The sections of the event
+// are defined once and copied into every session, so each
+// session keeps its own ticket counters
+type Session struct {
+	SessionID string      `json:"session_id"`
+	Date      time.Time   `json:"date"`
+	Status    EventStatus `json:"status"`
+
+	// moment when the session went to
+	// status "running", zero before that
+	StartedAt time.Time `json:"started_at"`
+
+	Sections []*Section `json:"sections"`
+}
+
+// AddSession adds a session on "date" to the event. The session gets a
+// copy of the current sections of the event. Sessions can be added while
+// the event is in status "draft" or "on_sale", and the new session starts
+// with the status of the event. Once an event has sessions, its tickets
+// must be sold for one of them, and the sessions are started and
+// finished instead of the event, so events with a schedule can not
+// have sessions. The caller must be the organizer or a delegate with
+// permission "edit_draft"
+//
+// Params
+// * - eventID   -> uuid format
+// * - sessionID -> uuid format
+// * - date      -> RFC3339 format
+//
+// The return value can be:
+// * - the session created serialized in JSON format
+// * - error in case some conditions to add the session are not fulfilled
+func (c *Contract) AddSession(ctx common.ITickenTxContext, eventID, sessionID, date string) (*Session, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionEditDraft); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft && event.Status != EventStatusOnSale {
+		return nil, ccErr("sessions can not be added to events on status %s", event.Status)
+	}
+
+	if event.Schedule != nil {
+		return nil, ccErr("sessions can not be added to events with a schedule")
+	}
+
+	sessionIDParsed, err := uuid.Parse(sessionID)
+	if err != nil {
+		return nil, ccErr("error parsing session id: %v", err)
+	}
+	if event.getSession(sessionIDParsed.String()) != nil {
+		return nil, ccErr("session with ID %s already exists", sessionID)
+	}
+
+	parsedDate, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return nil, ccErr("error parsing date: %v", err)
+	}
+
+	// events on sale already sold tickets without
+	// a session, so they can not be split anymore
+	if len(event.Sessions) == 0 && event.Status != EventStatusDraft {
+		return nil, ccErr("sessions can only be added to events on sale that already have sessions")
+	}
+
+	session := Session{
+		SessionID: sessionIDParsed.String(),
+		Date:      storedDate(parsedDate),
+		Status:    event.Status,
+		Sections:  make([]*Section, 0),
+	}
+
+	for _, section := range event.Sections {
+		session.Sections = append(session.Sections, section.copyFor(session.SessionID))
+	}
+
+	event.Sessions = append(event.Sessions, &session)
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &session, nil
+}
+
+// StartSession sets the session with ID "sessionID" to be on status
+// "running", so its tickets can be scanned and no more tickets are sold
+// for it. The event must be on sale. The caller must be the organizer
+// or a delegate with permission "start_finish"
+//
+// Params
+// * - eventID   -> uuid format
+// * - sessionID -> uuid format
+//
+// The return value can be:
+// * - error in case the session cant transition to state "running"
+func (c *Contract) StartSession(ctx common.ITickenTxContext, eventID, sessionID string) error {
+	return c.fireSessionTransition(ctx, eventID, sessionID, transitionStart)
+}
+
+// FinishSession sets the session with ID "sessionID" to be on status
+// "finished". The caller must be the organizer or a delegate with
+// permission "start_finish"
+//
+// Params
+// * - eventID   -> uuid format
+// * - sessionID -> uuid format
+//
+// The return value can be:
+// * - error in case the session cant transition to state "finished"
+func (c *Contract) FinishSession(ctx common.ITickenTxContext, eventID, sessionID string) error {
+	return c.fireSessionTransition(ctx, eventID, sessionID, transitionFinish)
+}
+
+func (event *Event) allSessionsFinished() bool {
+	if len(event.Sessions) == 0 {
+		return false
+	}
+
+	for _, session := range event.Sessions {
+		if session.Status != EventStatusFinished {
+			return false
+		}
+	}
+	return true
+}
+
+func (event *Event) getSession(sessionID string) *Session {
+	for _, session := range event.Sessions {
+		if session.SessionID == sessionID {
+			return session
+		}
+	}
+	return nil
+}
+
+// getSessionSection returns the section "sectionName" that holds the
+// tickets of the session "sessionID". Events without sessions hold the
+// tickets in their own sections, and "sessionID" must be empty
+func (event *Event) getSessionSection(sessionID, sectionName string) (*Section, error) {
+	if len(event.Sessions) == 0 {
+		if len(sessionID) > 0 {
+			return nil, ccErr("event %s has no sessions", event.EventID)
+		}
+
+		section := event.getSection(sectionName)
+		if section == nil {
+			return nil, ccErr("section %s doest not exist in event %s", sectionName, event.EventID)
+		}
+		return section, nil
+	}
+
+	if len(sessionID) == 0 {
+		return nil, ccErr("event %s has sessions and one of them must be chosen", event.EventID)
+	}
+
+	session := event.getSession(sessionID)
+	if session == nil {
+		return nil, ccErr("session %s doest not exist in event %s", sessionID, event.EventID)
+	}
+
+	for _, section := range session.Sections {
+		if section.Name == sectionName {
+			return section, nil
+		}
+	}
+
+	return nil, ccErr("section %s doest not exist in session %s", sectionName, sessionID)
+}
+
+// getSectionCopies returns the section "sectionName" of the event
+// and its copies in every session, so changes on the layout of the
+// event are applied to all of them. Nil is returned if the event
+// has no section with that name
+func (event *Event) getSectionCopies(sectionName string) []*Section {
+	section := event.getSection(sectionName)
+	if section == nil {
+		return nil
+	}
+
+	copies := []*Section{section}
+	for _, session := range event.Sessions {
+		for _, sessionSection := range session.Sections {
+			if sessionSection.Name == sectionName {
+				copies = append(copies, sessionSection)
+			}
+		}
+	}
+
+	return copies
+}
+
+// saleStatus returns the status that decides if the tickets of the
+// section can be sold: the status of the session of the section, or
+// the status of the event for events without sessions
+func (event *Event) saleStatus(section *Section) EventStatus {
+	if len(section.Session) == 0 {
+		return event.Status
+	}
+
+	session := event.getSession(section.Session)
+	if session == nil {
+		return event.Status
+	}
+
+	return session.Status
+}
+
+// copyFor returns a copy of the section without tickets
+// sold, to hold the tickets of the session "sessionID"
+func (section *Section) copyFor(sessionID string) *Section {
+	sessionSection := Section{
+		EventID:      section.EventID,
+		Session:      sessionID,
+		Name:         section.Name,
+		TicketPrice:  section.TicketPrice,
+		TotalTickets: section.TotalTickets,
+		SoldTickets:  0,
+		Area:         section.Area,
+		PriceTiers:   make([]*PriceTier, 0),
+		Allotments:   make([]*Allotment, 0),
+	}
+
+	for _, tier := range section.PriceTiers {
+		tierCopy := *tier
+		sessionSection.PriceTiers = append(sessionSection.PriceTiers, &tierCopy)
+	}
+
+	for _, allotment := range section.Allotments {
+		allotmentCopy := *allotment
+		allotmentCopy.Session = sessionID
+		allotmentCopy.IssuedTickets = 0
+		sessionSection.Allotments = append(sessionSection.Allotments, &allotmentCopy)
+	}
+
+	return &sessionSection
+}
@@ -0,0 +1,207 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"math"
+)
+
+// ********** cc-ticket integration ********** //
+
+const ccTicketName = "cc-ticket"
+const ccTicketGetEventTicketStatsFunc = "GetEventTicketStats"
+
+// ****************************************** //
+
+// sectionTicketStats is the count of the tickets
+// of a section returned by cc-ticket
+type sectionTicketStats struct {
+	Section        string                    `json:"section"`
+	Session        string                    `json:"session"`
+	ValidTickets   int                       `json:"valid_tickets"`
+	ScannedTickets int                       `json:"scanned_tickets"`
+	Revenue        []*common.CurrencyRevenue `json:"revenue"`
+}
+
+// SectionStats are the sales statistics of a section
+type SectionStats struct {
+	Section string `json:"section"`
+
+	// session of the section, empty
+	// for events without sessions
+	Session string `json:"session"`
+
+	TotalTickets int `json:"total_tickets"`
+	SoldTickets  int `json:"sold_tickets"`
+
+	// tickets that can still be sold to the public
+	RemainingTickets int `json:"remaining_tickets"`
+
+	// seats reserved by allotments
+	// that were not yet issued
+	HeldTickets int `json:"held_tickets"`
+
+	// percentage of the total tickets that were sold
+	SellThrough float64 `json:"sell_through"`
+
+	// amount collected by the tickets that were
+	// not voided, grouped by currency
+	Revenue []*common.CurrencyRevenue `json:"revenue"`
+
+	// true once the event, or the session of the section,
+	// is running. The scanned and no show tickets are only
+	// counted after that
+	AdmissionStarted bool `json:"admission_started"`
+	ScannedTickets   int  `json:"scanned_tickets"`
+
+	// valid tickets that were not scanned. While the
+	// event is running, they may still be scanned
+	NoShowTickets int `json:"no_show_tickets"`
+}
+
+// EventStats are the sales statistics of an event
+type EventStats struct {
+	EventID  string          `json:"event_id"`
+	Status   EventStatus     `json:"status"`
+	Sections []*SectionStats `json:"sections"`
+
+	// sum of the stats of all the sections,
+	// with empty section and session
+	Totals *SectionStats `json:"totals"`
+}
+
+// GetEventStats returns the sales statistics of every section of the
+// event with ID "eventID", and their totals. The counters are taken from
+// the event, while the revenue and the scanned tickets are read from
+// cc-ticket. For events with sessions, the sections of every session are
+// reported
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the stats of the event serialized in JSON format
+// * - error in case the event or its tickets could not be read
+func (c *Contract) GetEventStats(ctx common.ITickenTxContext, eventID string) (*EventStats, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	// events in draft can not have tickets
+	ticketStats := make([]*sectionTicketStats, 0)
+	if event.Status != EventStatusDraft {
+		ticketStats, err = getEventTicketStats(ctx, event.EventID)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+	}
+
+	sections := event.Sections
+	if len(event.Sessions) > 0 {
+		sections = make([]*Section, 0)
+		for _, session := range event.Sessions {
+			sections = append(sections, session.Sections...)
+		}
+	}
+
+	stats := EventStats{
+		EventID:  event.EventID,
+		Status:   event.Status,
+		Sections: make([]*SectionStats, 0),
+		Totals:   &SectionStats{Revenue: make([]*common.CurrencyRevenue, 0)},
+	}
+
+	for _, section := range sections {
+		sectionStats := SectionStats{
+			Section:          section.Name,
+			Session:          section.Session,
+			TotalTickets:     section.TotalTickets,
+			SoldTickets:      section.SoldTickets,
+			RemainingTickets: section.publicAvailableTickets(),
+			HeldTickets:      section.allottedTickets(),
+			Revenue:          make([]*common.CurrencyRevenue, 0),
+			AdmissionStarted: event.admissionStarted(section),
+		}
+
+		if tickets := findTicketStats(ticketStats, section); tickets != nil {
+			sectionStats.Revenue = tickets.Revenue
+			if sectionStats.AdmissionStarted {
+				sectionStats.ScannedTickets = tickets.ScannedTickets
+				sectionStats.NoShowTickets = tickets.ValidTickets - tickets.ScannedTickets
+			}
+		}
+
+		stats.Totals.add(&sectionStats)
+		sectionStats.SellThrough = sellThrough(sectionStats.SoldTickets, sectionStats.TotalTickets)
+		stats.Sections = append(stats.Sections, &sectionStats)
+	}
+
+	stats.Totals.SellThrough = sellThrough(stats.Totals.SoldTickets, stats.Totals.TotalTickets)
+	common.RoundRevenue(stats.Totals.Revenue)
+
+	return &stats, nil
+}
+
+func getEventTicketStats(ctx common.ITickenTxContext, eventID string) ([]*sectionTicketStats, error) {
+	statsJSON, err := ctx.GetInvoker(ccTicketName).Invoke(ccTicketGetEventTicketStatsFunc, eventID)
+	if err != nil {
+		return nil, ccErr("%s", err)
+	}
+
+	var stats []*sectionTicketStats
+	if err := json.Unmarshal(statsJSON, &stats); err != nil {
+		return nil, ccErr("failed to deserialize ticket stats: %v", err)
+	}
+
+	return stats, nil
+}
+
+func findTicketStats(ticketStats []*sectionTicketStats, section *Section) *sectionTicketStats {
+	for _, stats := range ticketStats {
+		if stats.Section == section.Name && stats.Session == section.Session {
+			return stats
+		}
+	}
+	return nil
+}
+
+// admissionStarted returns true if the tickets of the section can
+// be scanned or the admission already finished, either because the
+// event or the session of the section was started
+func (event *Event) admissionStarted(section *Section) bool {
+	if event.Status == EventStatusRunning || event.Status == EventStatusFinished {
+		return true
+	}
+
+	session := event.getSession(section.Session)
+	if session == nil {
+		return false
+	}
+
+	return session.Status == EventStatusRunning || session.Status == EventStatusFinished
+}
+
+// add sums the counters and the revenue of "stats" to the totals
+func (totals *SectionStats) add(stats *SectionStats) {
+	totals.TotalTickets += stats.TotalTickets
+	totals.SoldTickets += stats.SoldTickets
+	totals.RemainingTickets += stats.RemainingTickets
+	totals.HeldTickets += stats.HeldTickets
+	totals.ScannedTickets += stats.ScannedTickets
+	totals.NoShowTickets += stats.NoShowTickets
+	totals.AdmissionStarted = totals.AdmissionStarted || stats.AdmissionStarted
+
+	for _, revenue := range stats.Revenue {
+		totals.Revenue = common.AddRevenue(totals.Revenue, revenue.Currency, revenue.Amount)
+	}
+}
+
+// sellThrough returns the percentage of "total" that "sold"
+// represents, rounded to two decimals
+func sellThrough(sold, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(sold)*10000/float64(total)) / 100
+}
@@ -0,0 +1,149 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"reflect"
+	"testing"
+)
+
+// ticketStatsContract replaces cc-ticket,
+// returning always the same ticket stats
+type ticketStatsContract struct {
+	contractapi.Contract
+	stats []*sectionTicketStats
+}
+
+func (c *ticketStatsContract) GetEventTicketStats(eventID string) ([]*sectionTicketStats, error) {
+	return c.stats, nil
+}
+
+func newStatsContext(t *testing.T, event *Event, ticketStats []*sectionTicketStats) (*shimtest.MockStub, common.ITickenTxContext) {
+	ccTicket, err := contractapi.NewChaincode(&ticketStatsContract{stats: ticketStats})
+	if err != nil {
+		t.Fatalf("failed to create cc-ticket: %v", err)
+	}
+
+	stub := shimtest.NewMockStub(Name, nil)
+	stub.MockPeerChaincode(ccTicketName, shimtest.NewMockStub(ccTicketName, ccTicket), "")
+
+	ctx := common.NewTransactionContext()
+	ctx.SetStub(stub)
+
+	stub.MockTransactionStart("setup")
+	if err := new(Contract).putEvent(ctx, event); err != nil {
+		t.Fatalf("putEvent() error = %v", err)
+	}
+	stub.MockTransactionEnd("setup")
+
+	return stub, ctx
+}
+
+func TestGetEventStats(t *testing.T) {
+	const eventID = "22222222-2222-2222-2222-222222222222"
+
+	event := &Event{
+		EventID: eventID,
+		Status:  EventStatusRunning,
+		Sections: []*Section{
+			{
+				EventID:      eventID,
+				Name:         "vip",
+				TotalTickets: 10,
+				SoldTickets:  4,
+				Allotments:   []*Allotment{{Name: "press", Section: "vip", Tickets: 3, IssuedTickets: 1}},
+			},
+			{EventID: eventID, Name: "gen", TotalTickets: 20, SoldTickets: 2},
+			{EventID: eventID, Name: "box", TotalTickets: 5},
+		},
+	}
+
+	ticketStats := []*sectionTicketStats{
+		{
+			Section:        "vip",
+			ValidTickets:   4,
+			ScannedTickets: 3,
+			Revenue:        []*common.CurrencyRevenue{{Currency: "USD", Amount: 10.1}},
+		},
+		{
+			Section:        "gen",
+			ValidTickets:   2,
+			ScannedTickets: 2,
+			Revenue:        []*common.CurrencyRevenue{{Currency: "USD", Amount: 20.2}, {Currency: "EUR", Amount: 5}},
+		},
+	}
+
+	stub, ctx := newStatsContext(t, event, ticketStats)
+
+	stub.MockTransactionStart("stats")
+	stats, err := new(Contract).GetEventStats(ctx, eventID)
+	stub.MockTransactionEnd("stats")
+	if err != nil {
+		t.Fatalf("GetEventStats() error = %v", err)
+	}
+
+	usd := func(amount float64) *common.CurrencyRevenue {
+		return &common.CurrencyRevenue{Currency: "USD", Amount: amount}
+	}
+	eur := &common.CurrencyRevenue{Currency: "EUR", Amount: 5}
+
+	want := []*SectionStats{
+		{Section: "vip", TotalTickets: 10, SoldTickets: 4, RemainingTickets: 4, HeldTickets: 2, SellThrough: 40, Revenue: []*common.CurrencyRevenue{usd(10.1)}, AdmissionStarted: true, ScannedTickets: 3, NoShowTickets: 1},
+		{Section: "gen", TotalTickets: 20, SoldTickets: 2, RemainingTickets: 18, SellThrough: 10, Revenue: []*common.CurrencyRevenue{usd(20.2), eur}, AdmissionStarted: true, ScannedTickets: 2},
+		{Section: "box", TotalTickets: 5, RemainingTickets: 5, Revenue: []*common.CurrencyRevenue{}, AdmissionStarted: true},
+	}
+
+	// the revenue of the totals is rounded, so the
+	// floating point noise of 10.1 + 20.2 is removed
+	wantTotals := &SectionStats{TotalTickets: 35, SoldTickets: 6, RemainingTickets: 27, HeldTickets: 2, SellThrough: 17.14, Revenue: []*common.CurrencyRevenue{usd(30.3), eur}, AdmissionStarted: true, ScannedTickets: 5, NoShowTickets: 1}
+
+	if len(stats.Sections) != len(want) {
+		t.Fatalf("got the stats of %d sections, want %d", len(stats.Sections), len(want))
+	}
+	for i, sectionStats := range stats.Sections {
+		if !reflect.DeepEqual(sectionStats, want[i]) {
+			t.Errorf("section %d stats = %s, want %s", i, toJSON(sectionStats), toJSON(want[i]))
+		}
+	}
+
+	if !reflect.DeepEqual(stats.Totals, wantTotals) {
+		t.Errorf("totals = %s, want %s", toJSON(stats.Totals), toJSON(wantTotals))
+	}
+}
+
+func TestGetEventStatsOfDraftEvent(t *testing.T) {
+	const eventID = "22222222-2222-2222-2222-222222222222"
+
+	event := &Event{
+		EventID:  eventID,
+		Status:   EventStatusDraft,
+		Sections: []*Section{{EventID: eventID, Name: "vip", TotalTickets: 10}},
+	}
+
+	// events in draft can not have tickets, so
+	// the stats of cc-ticket must not be used
+	ticketStats := []*sectionTicketStats{{Section: "vip", ValidTickets: 1, ScannedTickets: 1}}
+
+	stub, ctx := newStatsContext(t, event, ticketStats)
+
+	stub.MockTransactionStart("stats")
+	stats, err := new(Contract).GetEventStats(ctx, eventID)
+	stub.MockTransactionEnd("stats")
+	if err != nil {
+		t.Fatalf("GetEventStats() error = %v", err)
+	}
+
+	if len(stats.Sections) != 1 {
+		t.Fatalf("got the stats of %d sections, want 1", len(stats.Sections))
+	}
+	if sectionStats := stats.Sections[0]; sectionStats.AdmissionStarted || sectionStats.ScannedTickets != 0 || sectionStats.RemainingTickets != 10 {
+		t.Errorf("section stats = %+v, want 10 remaining tickets and no admission", *sectionStats)
+	}
+}
+
+func toJSON(value any) string {
+	valueJSON, _ := json.Marshal(value)
+	return string(valueJSON)
+}
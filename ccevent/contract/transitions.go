@@ -0,0 +1,252 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"time"
+)
+
+// StatusChangedEvent is the name of the chaincode event emitted
+// when an event or one of its sessions changes its status. Its payload
+// is the list of the common.StateChange of the transaction, with entity
+// "event" or "session"
+const StatusChangedEvent = "StatusChanged"
+
+const (
+	transitionSell   = "sell"
+	transitionStart  = "start"
+	transitionFinish = "finish"
+
+	// transitions fired by Advance
+	// following the event schedule
+	transitionCloseSales = "close_sales"
+	transitionOpenDoors  = "open_doors"
+	transitionEnd        = "end"
+
+	// transition fired when the last
+	// session of the event finishes
+	transitionSessionsFinished = "sessions_finished"
+)
+
+// eventStateMachine returns the state
+// machine that drives the status of the events
+func (c *Contract) eventStateMachine() *common.StateMachine[*Event, EventStatus] {
+	return common.NewStateMachine(common.StateMachineConfig[*Event, EventStatus]{
+		Entity: "event",
+		ID:     func(event *Event) string { return event.EventID },
+		State:  func(event *Event) *EventStatus { return &event.Status },
+		Errorf: ccErr,
+
+		Transitions: []*common.Transition[*Event, EventStatus]{
+			{
+				// the event is published and its tickets can be sold.
+				// From this moment the event can not be modified
+				Name:      transitionSell,
+				From:      []EventStatus{EventStatusDraft},
+				To:        EventStatusOnSale,
+				Authorize: requireEventPermission(PermissionOpenSale),
+				Guards: []common.Guard[*Event]{
+					c.requireOrganizerEnabled,
+					checkVenueCapacity,
+				},
+				Action: func(ctx common.ITickenTxContext, event *Event) error {
+					for _, session := range event.Sessions {
+						session.Status = EventStatusOnSale
+					}
+					return nil
+				},
+				Event: StatusChangedEvent,
+			},
+			{
+				// no more tickets are sold and the
+				// tickets can be scanned
+				Name:      transitionStart,
+				From:      []EventStatus{EventStatusOnSale, EventStatusSalesClosed},
+				To:        EventStatusRunning,
+				Authorize: requireEventPermission(PermissionStartFinish),
+				Guards:    []common.Guard[*Event]{requireNoSessions},
+				Action:    setEventStartedAt,
+				Event:     StatusChangedEvent,
+			},
+			{
+				// the tickets are invalidated and become
+				// collectibles on the public blockchain
+				Name:      transitionFinish,
+				From:      []EventStatus{EventStatusRunning},
+				To:        EventStatusFinished,
+				Authorize: requireEventPermission(PermissionStartFinish),
+				Guards:    []common.Guard[*Event]{requireNoSessions},
+				Event:     StatusChangedEvent,
+			},
+			{
+				// the scheduled transitions are not authorized,
+				// their guards only let them fire on time
+				Name:   transitionCloseSales,
+				From:   []EventStatus{EventStatusOnSale},
+				To:     EventStatusSalesClosed,
+				Guards: []common.Guard[*Event]{requireNoSessions, scheduleReached((*Schedule).salesCloseAt)},
+				Event:  StatusChangedEvent,
+			},
+			{
+				Name:   transitionOpenDoors,
+				From:   []EventStatus{EventStatusOnSale, EventStatusSalesClosed},
+				To:     EventStatusRunning,
+				Guards: []common.Guard[*Event]{requireNoSessions, scheduleReached(func(schedule *Schedule) time.Time { return schedule.DoorsOpenAt })},
+				Action: setEventStartedAt,
+				Event:  StatusChangedEvent,
+			},
+			{
+				Name:   transitionEnd,
+				From:   []EventStatus{EventStatusRunning},
+				To:     EventStatusFinished,
+				Guards: []common.Guard[*Event]{requireNoSessions, scheduleReached(func(schedule *Schedule) time.Time { return schedule.EndsAt })},
+				Event:  StatusChangedEvent,
+			},
+			{
+				// events with sessions stay on sale while their
+				// sessions run, and they finish with the last one
+				Name: transitionSessionsFinished,
+				From: []EventStatus{EventStatusOnSale},
+				To:   EventStatusFinished,
+				Guards: []common.Guard[*Event]{
+					func(ctx common.ITickenTxContext, event *Event) error {
+						if !event.allSessionsFinished() {
+							return ccErr("event %s has sessions that did not finish", event.EventID)
+						}
+						return nil
+					},
+				},
+				Event: StatusChangedEvent,
+			},
+		},
+	})
+}
+
+// eventSession is a session together with its event, so
+// the session transitions can check the event status and
+// the permissions of the caller over the event
+type eventSession struct {
+	event   *Event
+	session *Session
+}
+
+// sessionStateMachine returns the state machine that drives the
+// status of the sessions. Sessions go on sale with their event, so
+// only start and finish are fired on them
+func (c *Contract) sessionStateMachine() *common.StateMachine[*eventSession, EventStatus] {
+	return common.NewStateMachine(common.StateMachineConfig[*eventSession, EventStatus]{
+		Entity: "session",
+		ID:     func(es *eventSession) string { return es.session.SessionID },
+		State:  func(es *eventSession) *EventStatus { return &es.session.Status },
+		Errorf: ccErr,
+
+		Transitions: []*common.Transition[*eventSession, EventStatus]{
+			{
+				Name:      transitionStart,
+				From:      []EventStatus{EventStatusOnSale},
+				To:        EventStatusRunning,
+				Authorize: requireSessionPermission(PermissionStartFinish),
+				Guards: []common.Guard[*eventSession]{
+					func(ctx common.ITickenTxContext, es *eventSession) error {
+						if es.event.Status != EventStatusOnSale {
+							return ccErr("event not on sale")
+						}
+						return nil
+					},
+				},
+				Action: func(ctx common.ITickenTxContext, es *eventSession) error {
+					startedAt, err := ctx.Now()
+					if err != nil {
+						return ccErr("failed to get transaction time: %v", err)
+					}
+					es.session.StartedAt = startedAt
+					return nil
+				},
+				Event: StatusChangedEvent,
+			},
+			{
+				Name:      transitionFinish,
+				From:      []EventStatus{EventStatusRunning},
+				To:        EventStatusFinished,
+				Authorize: requireSessionPermission(PermissionStartFinish),
+				Event:     StatusChangedEvent,
+			},
+		},
+	})
+}
+
+// fireEventTransition fires the transition "name"
+// on the event with ID "eventID" and saves it
+func (c *Contract) fireEventTransition(ctx common.ITickenTxContext, eventID, name string) error {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if err := c.eventStateMachine().Fire(ctx, name, event); err != nil {
+		return err // this error is already formatted
+	}
+
+	return c.putEvent(ctx, event)
+}
+
+// fireSessionTransition fires the transition "name" on the session
+// with ID "sessionID" of the event and saves the event. When the last
+// session finishes, the event is finished too
+func (c *Contract) fireSessionTransition(ctx common.ITickenTxContext, eventID, sessionID, name string) error {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	session := event.getSession(sessionID)
+	if session == nil {
+		return ccErr("session %s doest not exist in event %s", sessionID, eventID)
+	}
+
+	if err := c.sessionStateMachine().Fire(ctx, name, &eventSession{event, session}); err != nil {
+		return err // this error is already formatted
+	}
+
+	if name == transitionFinish && event.allSessionsFinished() {
+		if err := c.eventStateMachine().Fire(ctx, transitionSessionsFinished, event); err != nil {
+			return err // this error is already formatted
+		}
+	}
+
+	return c.putEvent(ctx, event)
+}
+
+func setEventStartedAt(ctx common.ITickenTxContext, event *Event) error {
+	startedAt, err := ctx.Now()
+	if err != nil {
+		return ccErr("failed to get transaction time: %v", err)
+	}
+	event.StartedAt = startedAt
+	return nil
+}
+
+// requireNoSessions fails for events with sessions. Their tickets are
+// scanned with the status of their sessions, so the event can not be
+// started or finished as a whole
+func requireNoSessions(ctx common.ITickenTxContext, event *Event) error {
+	if len(event.Sessions) > 0 {
+		return ccErr("event %s has sessions, they must be started and finished one by one", event.EventID)
+	}
+	return nil
+}
+
+func requireEventPermission(permission Permission) common.Guard[*Event] {
+	return func(ctx common.ITickenTxContext, event *Event) error {
+		return checkPermission(ctx, event, permission)
+	}
+}
+
+func requireSessionPermission(permission Permission) common.Guard[*eventSession] {
+	return func(ctx common.ITickenTxContext, es *eventSession) error {
+		return checkPermission(ctx, es.event, permission)
+	}
+}
+
+func (c *Contract) requireOrganizerEnabled(ctx common.ITickenTxContext, event *Event) error {
+	return c.checkOrganizerEnabled(ctx, event.MSPID, event.OrganizerUsername)
+}
@@ -0,0 +1,175 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+)
+
+// Validator is an identity (usually door staff) allowed
+// to scan the tickets of an event at the given gates
+type Validator struct {
+	MSPID    string `json:"msp_id"`
+	Username string `json:"username"`
+
+	// gates where the validator can scan tickets.
+	// Empty means that it can scan at any gate
+	Gates []string `json:"gates"`
+}
+
+// AssignValidator allows the identity "mspID"/"username" to scan the
+// tickets of the event at the gates "gates". Assigning an identity
+// that is already a validator replaces its gates. The caller must
+// be the organizer or a delegate with permission "manage_validators"
+//
+// Params
+// * - eventID  -> uuid format
+// * - mspID    -> MSP of the validator identity
+// * - username -> username of the validator identity
+// * - gates    -> names of the gates (empty for all the gates)
+//
+// The return value can be:
+// * - the validator assigned serialized in JSON format
+// * - error in case the caller is not allowed to manage validators
+func (c *Contract) AssignValidator(ctx common.ITickenTxContext, eventID, mspID, username string, gates []string) (*Validator, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionManageValidators); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status == EventStatusFinished {
+		return nil, ccErr("event %s is finished", eventID)
+	}
+
+	if len(mspID) == 0 || len(username) == 0 {
+		return nil, ccErr("validator msp id and username are required")
+	}
+
+	if gates == nil {
+		gates = make([]string, 0)
+	}
+
+	validator := event.getValidator(mspID, username)
+	if validator == nil {
+		validator = &Validator{
+			MSPID:    mspID,
+			Username: username,
+		}
+		event.Validators = append(event.Validators, validator)
+	}
+	validator.Gates = gates
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return validator, nil
+}
+
+// RevokeValidator removes the identity "mspID"/"username" from the
+// validators of the event. The caller must be the organizer or a
+// delegate with permission "manage_validators"
+//
+// Params
+// * - eventID  -> uuid format
+// * - mspID    -> MSP of the validator identity
+// * - username -> username of the validator identity
+//
+// The return value can be:
+// * - error in case the validator is not found or the caller is not allowed
+func (c *Contract) RevokeValidator(ctx common.ITickenTxContext, eventID, mspID, username string) error {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionManageValidators); err != nil {
+		return err // this error is already formatted
+	}
+
+	remainingValidators := make([]*Validator, 0)
+	for _, validator := range event.Validators {
+		if validator.MSPID != mspID || validator.Username != username {
+			remainingValidators = append(remainingValidators, validator)
+		}
+	}
+
+	if len(remainingValidators) == len(event.Validators) {
+		return ccErr("%s@%s is not a validator of event %s", username, mspID, eventID)
+	}
+
+	event.Validators = remainingValidators
+
+	return c.putEvent(ctx, event)
+}
+
+// ListValidators returns the validators assigned to the event
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the list of validators (possibly empty)
+// * - error in case of the event is not found
+func (c *Contract) ListValidators(ctx common.ITickenTxContext, eventID string) ([]*Validator, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Validators == nil {
+		return make([]*Validator, 0), nil
+	}
+
+	return event.Validators, nil
+}
+
+// IsValidator returns true if the identity "mspID"/"username" is
+// allowed to scan tickets of the event at the gate "gate". This is
+// used by cc-ticket to authorize the identity that scans a ticket
+//
+// Params
+// * - eventID  -> uuid format
+// * - mspID    -> MSP of the validator identity
+// * - username -> username of the validator identity
+// * - gate     -> name of the gate where the ticket is scanned
+//
+// The return value can be:
+// * - true if the identity is authorized, false otherwise
+// * - error in case of the event is not found
+func (c *Contract) IsValidator(ctx common.ITickenTxContext, eventID, mspID, username, gate string) (bool, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return false, err // this error is already formatted
+	}
+
+	validator := event.getValidator(mspID, username)
+	if validator == nil {
+		return false, nil
+	}
+
+	return validator.canScanAt(gate), nil
+}
+
+func (event *Event) getValidator(mspID, username string) *Validator {
+	for _, validator := range event.Validators {
+		if validator.MSPID == mspID && validator.Username == username {
+			return validator
+		}
+	}
+	return nil
+}
+
+func (validator *Validator) canScanAt(gate string) bool {
+	if len(validator.Gates) == 0 {
+		return true
+	}
+	for _, g := range validator.Gates {
+		if g == gate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+)
+
+// ********** cc-venue integration ********** //
+
+const ccVenueName = "cc-venue"
+const ccVenueGetVenueFunc = "GetVenue"
+
+// ****************************************** //
+
+// venue is the subset of the venue registered
+// in cc-venue that is needed by the events
+type venue struct {
+	VenueID  string       `json:"venue_id"`
+	Capacity int          `json:"capacity"`
+	Areas    []*venueArea `json:"areas"`
+}
+
+type venueArea struct {
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"`
+}
+
+func getVenue(ctx common.ITickenTxContext, venueID string) (*venue, error) {
+	venueJSON, err := ctx.GetInvoker(ccVenueName).Invoke(ccVenueGetVenueFunc, venueID)
+	if err != nil {
+		return nil, ccErr("%s", err)
+	}
+
+	var v venue
+	if err := json.Unmarshal(venueJSON, &v); err != nil {
+		return nil, ccErr("failed to deserialize venue: %v", err)
+	}
+
+	return &v, nil
+}
+
+// SetVenue sets the venue where the event takes place. Events created
+// before the venues were introduced have none, and they can not add
+// sections or go on sale until it is set. The venue can only be changed
+// while the event is in status "draft", and the sections of the event
+// must fit in it. The caller must be the organizer or a delegate with
+// permission "edit_draft"
+//
+// Params
+// * - eventID -> uuid format
+// * - venueID -> uuid format (must be registered in cc-venue)
+//
+// The return value can be:
+// * - the event updated serialized in JSON format
+// * - error in case some conditions to set the venue are not fulfilled
+func (c *Contract) SetVenue(ctx common.ITickenTxContext, eventID, venueID string) (*Event, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkPermission(ctx, event, PermissionEditDraft); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if event.Status != EventStatusDraft {
+		return nil, ccErr("event is not in status draft")
+	}
+
+	v, err := getVenue(ctx, venueID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+	event.VenueID = v.VenueID
+
+	if err := checkVenueCapacity(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := c.putEvent(ctx, event); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return event, nil
+}
+
+// checkVenueCapacity verifies that the total tickets of the event
+// sections fit in the venue capacity, and that the sections placed
+// in a venue area fit in the capacity of that area. Events without
+// a venue, created before the venues were introduced, are rejected
+func checkVenueCapacity(ctx common.ITickenTxContext, event *Event) error {
+	if len(event.VenueID) == 0 {
+		return ccErr("event %s has no venue - it must be set with SetVenue", event.EventID)
+	}
+
+	v, err := getVenue(ctx, event.VenueID)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	totalTickets := 0
+	areasTickets := make(map[string]int)
+	for _, section := range event.Sections {
+		totalTickets += section.TotalTickets
+		if len(section.Area) > 0 {
+			areasTickets[section.Area] += section.TotalTickets
+		}
+	}
+
+	if totalTickets > v.Capacity {
+		return ccErr("sections total tickets %d exceed venue capacity %d", totalTickets, v.Capacity)
+	}
+
+	// sections are walked instead of the map
+	// so the errors are the same on every peer
+	for _, section := range event.Sections {
+		if len(section.Area) == 0 {
+			continue
+		}
+
+		area := v.getArea(section.Area)
+		if area == nil {
+			return ccErr("area %s does not exist in venue %s", section.Area, v.VenueID)
+		}
+		if areasTickets[area.Name] > area.Capacity {
+			return ccErr("sections in area %s have %d tickets and exceed its capacity %d", area.Name, areasTickets[area.Name], area.Capacity)
+		}
+	}
+
+	return nil
+}
+
+func (v *venue) getArea(name string) *venueArea {
+	for _, area := range v.Areas {
+		if area.Name == name {
+			return area
+		}
+	}
+	return nil
+}
@@ -0,0 +1,535 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"time"
+)
+
+const waitlistIndex = "waitlist~eventID~section"
+
+// WaitlistClaimWindow is the time an owner has to buy the seat
+// offered to them before it is offered to the next owner
+const WaitlistClaimWindow = 30 * time.Minute
+
+// WaitlistEntry is an owner waiting for
+// a seat in a sold out section
+type WaitlistEntry struct {
+	OwnerID  string    `json:"owner"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// WaitlistOffer is a seat reserved for a waitlisted owner,
+// that only they can buy until the offer expires
+type WaitlistOffer struct {
+	EventID   string    `json:"event_id"`
+	Session   string    `json:"session"`
+	Section   string    `json:"section"`
+	OwnerID   string    `json:"owner"`
+	OfferedAt time.Time `json:"offered_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Waitlist holds the owners waiting for a seat in a section
+// in the order they joined, and the offers not yet claimed
+type Waitlist struct {
+	EventID string           `json:"event_id"`
+	Session string           `json:"session"`
+	Section string           `json:"section"`
+	Entries []*WaitlistEntry `json:"entries"`
+	Offers  []*WaitlistOffer `json:"offers"`
+}
+
+// WaitlistPosition is the place of an owner in a waitlist
+type WaitlistPosition struct {
+	EventID string `json:"event_id"`
+	Session string `json:"session"`
+	Section string `json:"section"`
+	OwnerID string `json:"owner"`
+
+	// 1 for the next owner to receive an offer,
+	// 0 if the owner already has an offer
+	Position int `json:"position"`
+
+	// present only if the owner has an offer
+	Offer *WaitlistOffer `json:"offer" metadata:",optional"`
+}
+
+// JoinWaitlist adds the owner with ID "ownerID" at the end of the
+// waitlist of the section "sectionName". The section must be sold
+// out. When a seat is released, it is offered to the first owner
+// of the waitlist, who can buy it during WaitlistClaimWindow. The
+// owners are not identities of the network, so the caller must be
+// the ticket service acting on their behalf
+//
+// Params
+// * - eventID     -> uuid format
+// * - sessionID   -> uuid format (must be empty for events without sessions)
+// * - sectionName -> unique name that identifies the section in the event
+// * - ownerID     -> uuid format
+//
+// The return value can be:
+// * - the position of the owner serialized in JSON format
+// * - error in case the owner can not join the waitlist
+func (c *Contract) JoinWaitlist(ctx common.ITickenTxContext, eventID, sessionID, sectionName, ownerID string) (*WaitlistPosition, error) {
+	if err := checkTicketService(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	event, section, err := c.getSaleSection(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if _, err := uuid.Parse(ownerID); err != nil {
+		return nil, ccErr("error parsing owner id: %v", err)
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	waitlist, err := getWaitlist(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	waitlist.expireOffers(now)
+	newOffers := waitlist.offerSeats(event, section, now)
+
+	if waitlist.hasOwner(ownerID) {
+		return nil, ccErr("owner %s is already in the waitlist of section %s", ownerID, sectionName)
+	}
+
+	if waitlist.availableTickets(section) > 0 {
+		return nil, ccErr("section %s has available tickets", sectionName)
+	}
+
+	waitlist.Entries = append(waitlist.Entries, &WaitlistEntry{
+		OwnerID:  ownerID,
+		JoinedAt: now,
+	})
+
+	if err := saveWaitlist(ctx, waitlist); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := common.EmitWaitlistOffers(ctx, newOffers); err != nil {
+		return nil, ccErr("failed to emit waitlist offers: %v", err)
+	}
+
+	return waitlist.getPosition(ownerID), nil
+}
+
+// LeaveWaitlist removes the owner with ID "ownerID" from the waitlist
+// of the section "sectionName". If the owner had an offer, the seat
+// is offered to the next owner of the waitlist. The caller must be
+// the ticket service acting on behalf of the owner
+//
+// Params
+// * - eventID     -> uuid format
+// * - sessionID   -> uuid format (must be empty for events without sessions)
+// * - sectionName -> unique name that identifies the section in the event
+// * - ownerID     -> uuid format
+//
+// The return value can be:
+// * - error in case the owner is not in the waitlist
+func (c *Contract) LeaveWaitlist(ctx common.ITickenTxContext, eventID, sessionID, sectionName, ownerID string) error {
+	if err := checkTicketService(ctx); err != nil {
+		return err // this error is already formatted
+	}
+
+	event, section, err := c.getEventSection(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return ccErr("failed to get transaction time: %v", err)
+	}
+
+	waitlist, err := getWaitlist(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	waitlist.expireOffers(now)
+
+	if !waitlist.hasOwner(ownerID) {
+		return ccErr("owner %s is not in the waitlist of section %s", ownerID, sectionName)
+	}
+	waitlist.removeOwner(ownerID)
+
+	newOffers := waitlist.offerSeats(event, section, now)
+
+	if err := saveWaitlist(ctx, waitlist); err != nil {
+		return err // this error is already formatted
+	}
+
+	if err := common.EmitWaitlistOffers(ctx, newOffers); err != nil {
+		return ccErr("failed to emit waitlist offers: %v", err)
+	}
+
+	return nil
+}
+
+// GetWaitlistPosition returns the position of the owner with ID
+// "ownerID" in the waitlist of the section "sectionName", and the
+// offer made to them if any
+//
+// Params
+// * - eventID     -> uuid format
+// * - sessionID   -> uuid format (must be empty for events without sessions)
+// * - sectionName -> unique name that identifies the section in the event
+// * - ownerID     -> uuid format
+//
+// The return value can be:
+// * - the position of the owner serialized in JSON format
+// * - error in case the owner is not in the waitlist
+func (c *Contract) GetWaitlistPosition(ctx common.ITickenTxContext, eventID, sessionID, sectionName, ownerID string) (*WaitlistPosition, error) {
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	waitlist, err := getWaitlist(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	// expired offers are not reported, even
+	// if they were not yet removed from the state
+	waitlist.expireOffers(now)
+
+	position := waitlist.getPosition(ownerID)
+	if position == nil {
+		return nil, ccErr("owner %s is not in the waitlist of section %s", ownerID, sectionName)
+	}
+
+	return position, nil
+}
+
+// GetWaitlist returns the owners waiting for a seat in the
+// section "sectionName" and the offers not yet claimed
+//
+// Params
+// * - eventID     -> uuid format
+// * - sessionID   -> uuid format (must be empty for events without sessions)
+// * - sectionName -> unique name that identifies the section in the event
+//
+// The return value can be:
+// * - the waitlist of the section serialized in JSON format
+// * - error in case the waitlist could not be read
+func (c *Contract) GetWaitlist(ctx common.ITickenTxContext, eventID, sessionID, sectionName string) (*Waitlist, error) {
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	waitlist, err := getWaitlist(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	waitlist.expireOffers(now)
+
+	return waitlist, nil
+}
+
+// ExpireWaitlistOffers removes the offers of the section "sectionName"
+// whose claim window has passed and offers their seats to the next
+// owners of the waitlist. Offers are also expired by any other
+// waitlist or sale transaction of the section, so this only needs
+// to be called when the section has no activity. The caller must
+// be the ticket service
+//
+// Params
+// * - eventID     -> uuid format
+// * - sessionID   -> uuid format (must be empty for events without sessions)
+// * - sectionName -> unique name that identifies the section in the event
+//
+// The return value can be:
+// * - the new offers made (possibly empty)
+// * - error in case the waitlist could not be updated
+func (c *Contract) ExpireWaitlistOffers(ctx common.ITickenTxContext, eventID, sessionID, sectionName string) ([]*WaitlistOffer, error) {
+	if err := checkTicketService(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	event, section, err := c.getEventSection(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return offerReleasedSeats(ctx, event, section)
+}
+
+// offerReleasedSeats offers the seats of the sections that are not
+// sold nor reserved to the next owners of their waitlists, emitting
+// the common.WaitlistOffersEvent. It must be called after modifying
+// the sections, and it returns the offers made
+func offerReleasedSeats(ctx common.ITickenTxContext, event *Event, sections ...*Section) ([]*WaitlistOffer, error) {
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	newOffers := make([]*WaitlistOffer, 0)
+	for _, section := range sections {
+		waitlist, err := getWaitlist(ctx, event.EventID, section.Session, section.Name)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+
+		waitlist.expireOffers(now)
+		newOffers = append(newOffers, waitlist.offerSeats(event, section, now)...)
+
+		if err := saveWaitlist(ctx, waitlist); err != nil {
+			return nil, err // this error is already formatted
+		}
+	}
+
+	if err := common.EmitWaitlistOffers(ctx, newOffers); err != nil {
+		return nil, ccErr("failed to emit waitlist offers: %v", err)
+	}
+
+	return newOffers, nil
+}
+
+// claimWaitlistSeat is called when a ticket of the section is sold to
+// the owner with ID "ownerID". If the owner has an offer, it is used.
+// Otherwise, the sale fails when all the available seats are reserved.
+// The offers made while expiring the old ones are returned
+func claimWaitlistSeat(ctx common.ITickenTxContext, event *Event, section *Section, ownerID string) ([]*WaitlistOffer, error) {
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	waitlist, err := getWaitlist(ctx, event.EventID, section.Session, section.Name)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if len(waitlist.Entries) == 0 && len(waitlist.Offers) == 0 {
+		return make([]*WaitlistOffer, 0), nil
+	}
+
+	waitlist.expireOffers(now)
+	newOffers := waitlist.offerSeats(event, section, now)
+
+	if waitlist.getOffer(ownerID) == nil && waitlist.availableTickets(section) <= 0 {
+		return nil, ccErr("section %s is full", section.Name)
+	}
+
+	// the owner leaves the waitlist
+	// once they buy a ticket
+	waitlist.removeOwner(ownerID)
+
+	if err := saveWaitlist(ctx, waitlist); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := common.EmitWaitlistOffers(ctx, newOffers); err != nil {
+		return nil, ccErr("failed to emit waitlist offers: %v", err)
+	}
+
+	return newOffers, nil
+}
+
+// getSaleSection returns the event and the section "sectionName" of
+// the session "sessionID", checking that the event is on sale
+func (c *Contract) getSaleSection(ctx common.ITickenTxContext, eventID, sessionID, sectionName string) (*Event, *Section, error) {
+	event, section, err := c.getEventSection(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return nil, nil, err // this error is already formatted
+	}
+
+	if event.saleStatus(section) != EventStatusOnSale {
+		return nil, nil, ccErr("event not on sale")
+	}
+
+	return event, section, nil
+}
+
+// getEventSection returns the event and the section "sectionName"
+// that holds the tickets of the session "sessionID"
+func (c *Contract) getEventSection(ctx common.ITickenTxContext, eventID, sessionID, sectionName string) (*Event, *Section, error) {
+	event, err := c.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, nil, err // this error is already formatted
+	}
+
+	section, err := event.getSessionSection(sessionID, sectionName)
+	if err != nil {
+		return nil, nil, err // this error is already formatted
+	}
+
+	return event, section, nil
+}
+
+func getWaitlist(ctx common.ITickenTxContext, eventID, sessionID, sectionName string) (*Waitlist, error) {
+	waitlistKey, err := createWaitlistKey(ctx, eventID, sessionID, sectionName)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	waitlistJSON, err := ctx.GetStub().GetState(waitlistKey)
+	if err != nil {
+		return nil, ccErr("failed to read waitlist: %v", err)
+	}
+
+	waitlist := Waitlist{
+		EventID: eventID,
+		Session: sessionID,
+		Section: sectionName,
+		Entries: make([]*WaitlistEntry, 0),
+		Offers:  make([]*WaitlistOffer, 0),
+	}
+
+	if waitlistJSON != nil {
+		if err := json.Unmarshal(waitlistJSON, &waitlist); err != nil {
+			return nil, ccErr("failed to deserialize waitlist: %v", err)
+		}
+	}
+
+	return &waitlist, nil
+}
+
+// createWaitlistKey returns the key of the waitlist of the section.
+// The session is only part of the key for events with sessions, so
+// the waitlists of events without sessions keep their keys
+func createWaitlistKey(ctx common.ITickenTxContext, eventID, sessionID, sectionName string) (string, error) {
+	attributes := []string{eventID, sectionName}
+	if len(sessionID) > 0 {
+		attributes = append(attributes, sessionID)
+	}
+
+	waitlistKey, err := ctx.GetStub().CreateCompositeKey(waitlistIndex, attributes)
+	if err != nil {
+		return "", ccErr("failed to create waitlist key: %v", err)
+	}
+
+	return waitlistKey, nil
+}
+
+func saveWaitlist(ctx common.ITickenTxContext, waitlist *Waitlist) error {
+	waitlistKey, err := createWaitlistKey(ctx, waitlist.EventID, waitlist.Session, waitlist.Section)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	waitlistJSON, err := json.Marshal(waitlist)
+	if err != nil {
+		return ccErr("failed to serialize waitlist: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(waitlistKey, waitlistJSON); err != nil {
+		return ccErr("failed to update ledger: %v", err)
+	}
+
+	return nil
+}
+
+// expireOffers removes the offers whose claim window has passed.
+// The owners of those offers lose their place in the waitlist
+func (waitlist *Waitlist) expireOffers(now time.Time) {
+	activeOffers := make([]*WaitlistOffer, 0)
+	for _, offer := range waitlist.Offers {
+		if now.Before(offer.ExpiresAt) {
+			activeOffers = append(activeOffers, offer)
+		}
+	}
+	waitlist.Offers = activeOffers
+}
+
+// offerSeats offers each seat of the section that is neither sold nor
+// reserved to the next owner of the waitlist. Seats are only offered
+// while the event and the session of the section are on sale
+func (waitlist *Waitlist) offerSeats(event *Event, section *Section, now time.Time) []*WaitlistOffer {
+	newOffers := make([]*WaitlistOffer, 0)
+	if event.saleStatus(section) != EventStatusOnSale {
+		return newOffers
+	}
+
+	for waitlist.availableTickets(section) > 0 && len(waitlist.Entries) > 0 {
+		entry := waitlist.Entries[0]
+		waitlist.Entries = waitlist.Entries[1:]
+
+		offer := &WaitlistOffer{
+			EventID:   waitlist.EventID,
+			Session:   waitlist.Session,
+			Section:   waitlist.Section,
+			OwnerID:   entry.OwnerID,
+			OfferedAt: now,
+			ExpiresAt: now.Add(WaitlistClaimWindow),
+		}
+		waitlist.Offers = append(waitlist.Offers, offer)
+		newOffers = append(newOffers, offer)
+	}
+
+	return newOffers
+}
+
+// availableTickets returns the amount of tickets of the
+// section that can be bought by owners without an offer
+func (waitlist *Waitlist) availableTickets(section *Section) int {
+	return section.publicAvailableTickets() - len(waitlist.Offers)
+}
+
+func (waitlist *Waitlist) hasOwner(ownerID string) bool {
+	return waitlist.getPosition(ownerID) != nil
+}
+
+func (waitlist *Waitlist) getOffer(ownerID string) *WaitlistOffer {
+	for _, offer := range waitlist.Offers {
+		if offer.OwnerID == ownerID {
+			return offer
+		}
+	}
+	return nil
+}
+
+func (waitlist *Waitlist) getPosition(ownerID string) *WaitlistPosition {
+	position := WaitlistPosition{
+		EventID: waitlist.EventID,
+		Session: waitlist.Session,
+		Section: waitlist.Section,
+		OwnerID: ownerID,
+	}
+
+	if offer := waitlist.getOffer(ownerID); offer != nil {
+		position.Offer = offer
+		return &position
+	}
+
+	for i, entry := range waitlist.Entries {
+		if entry.OwnerID == ownerID {
+			position.Position = i + 1
+			return &position
+		}
+	}
+
+	return nil
+}
+
+func (waitlist *Waitlist) removeOwner(ownerID string) {
+	remainingEntries := make([]*WaitlistEntry, 0)
+	for _, entry := range waitlist.Entries {
+		if entry.OwnerID != ownerID {
+			remainingEntries = append(remainingEntries, entry)
+		}
+	}
+	waitlist.Entries = remainingEntries
+
+	remainingOffers := make([]*WaitlistOffer, 0)
+	for _, offer := range waitlist.Offers {
+		if offer.OwnerID != ownerID {
+			remainingOffers = append(remainingOffers, offer)
+		}
+	}
+	waitlist.Offers = remainingOffers
+}
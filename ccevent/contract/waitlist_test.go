@@ -0,0 +1,80 @@
+package contract
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strings"
+	"testing"
+)
+
+func TestWaitlistAuthorization(t *testing.T) {
+	const eventID = "22222222-2222-2222-2222-222222222222"
+	const waitingOwnerID = "44444444-4444-4444-4444-444444444441"
+	const ownerID = "44444444-4444-4444-4444-444444444442"
+
+	join := func(ownerID string) func(ctx common.ITickenTxContext) error {
+		return func(ctx common.ITickenTxContext) error {
+			_, err := new(Contract).JoinWaitlist(ctx, eventID, "", "vip", ownerID)
+			return err
+		}
+	}
+	leave := func(ctx common.ITickenTxContext) error {
+		return new(Contract).LeaveWaitlist(ctx, eventID, "", "vip", waitingOwnerID)
+	}
+	expire := func(ctx common.ITickenTxContext) error {
+		_, err := new(Contract).ExpireWaitlistOffers(ctx, eventID, "", "vip")
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		identity testIdentity
+		tx       func(ctx common.ITickenTxContext) error
+		wantErr  string
+	}{
+		{name: "join by the ticket service", identity: ticketServiceIdentity, tx: join(ownerID)},
+		{name: "join by the organizer", identity: organizerIdentity, tx: join(ownerID), wantErr: "only the ticket service"},
+		{name: "join by a platform admin", identity: adminIdentity, tx: join(ownerID), wantErr: "only the ticket service"},
+		{name: "join with an invalid owner", identity: ticketServiceIdentity, tx: join("owner"), wantErr: "error parsing owner id"},
+		{name: "leave by the ticket service", identity: ticketServiceIdentity, tx: leave},
+		{name: "leave by the organizer", identity: organizerIdentity, tx: leave, wantErr: "only the ticket service"},
+		{name: "expire by the ticket service", identity: ticketServiceIdentity, tx: expire},
+		{name: "expire by the organizer", identity: organizerIdentity, tx: expire, wantErr: "only the ticket service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the section is sold out, so
+			// owners can join its waitlist
+			stub := shimtest.NewMockStub(Name, nil)
+			putTestEvent(t, stub, &Event{
+				EventID:           eventID,
+				Status:            EventStatusOnSale,
+				MSPID:             organizerIdentity.mspID,
+				OrganizerUsername: organizerIdentity.username,
+				Sections:          []*Section{{EventID: eventID, Name: "vip", TotalTickets: 1, SoldTickets: 1}},
+			})
+
+			stub.MockTransactionStart("setup")
+			err := join(waitingOwnerID)(newTestContext(stub, ticketServiceIdentity))
+			stub.MockTransactionEnd("setup")
+			if err != nil {
+				t.Fatalf("JoinWaitlist() error = %v", err)
+			}
+
+			stub.MockTransactionStart("tx")
+			err = tt.tx(newTestContext(stub, tt.identity))
+			stub.MockTransactionEnd("tx")
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+)
+
+// verifyAllowlist asks cc-event for the allowlist of the sale phase
+// that will sell the ticket and checks that the owner belongs to it.
+// The leaves of the allowlist are the owner IDs in uuid format
+func verifyAllowlist(ctx common.ITickenTxContext, eventID, section, ownerID string, merkleProof []string) error {
+	rootHex, err := ctx.GetInvoker(ccEventName).Invoke(ccEventGetSectionAllowlistRootFunc, eventID, section)
+	if err != nil {
+		return ccErr("%s", err)
+	}
+
+	// the sale phase is open
+	// to all the owners
+	if len(rootHex) == 0 {
+		return nil
+	}
+
+	root, err := common.ParseMerkleHash(string(rootHex))
+	if err != nil {
+		return ccErr("invalid allowlist root: %v", err)
+	}
+
+	proof, err := common.ParseMerkleProof(merkleProof)
+	if err != nil {
+		return ccErr("invalid merkle proof: %v", err)
+	}
+
+	if !common.VerifyMerkleProof(root, common.MerkleLeaf(ownerID), proof) {
+		return ccErr("owner %s is not allowed to buy in the current sale phase", ownerID)
+	}
+
+	return nil
+}
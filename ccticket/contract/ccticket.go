@@ -8,6 +8,8 @@ import (
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/ticken-ts/ticken-chaincodes/common"
 	"math/big"
+	"strings"
+	"time"
 )
 
 type Contract struct {
@@ -18,6 +20,65 @@ type Contract struct {
 
 const ccEventName = "cc-event"
 const ccEventSellTicketFunc = "SellTicket"
+const ccEventGetSectionAllowlistRootFunc = "GetSectionAllowlistRoot"
+const ccEventIsValidatorFunc = "IsValidator"
+const ccEventGetEventFunc = "GetEvent"
+const ccEventReleaseTicketFunc = "ReleaseTicket"
+const ccEventSetSoldTicketsBatchFunc = "SetSoldTicketsBatch"
+const ccEventIssueAllotmentTicketFunc = "IssueAllotmentTicket"
+const ccEventSellPassFunc = "SellPass"
+const ccEventGetSeriesFunc = "GetSeries"
+
+// event is the subset of the event
+// stored in cc-event used by the tickets
+type event struct {
+	EventID   string    `json:"event_id"`
+	Status    string    `json:"status"`
+	Date      time.Time `json:"date"`
+	StartedAt time.Time `json:"started_at"`
+
+	MSPID             string `json:"msp_id"`
+	OrganizerUsername string `json:"organizer_username"`
+
+	RefundPolicy *refundPolicy `json:"refund_policy"`
+
+	Sections   []*section   `json:"sections"`
+	Sessions   []*session   `json:"sessions"`
+	SalePhases []*salePhase `json:"sale_phases"`
+}
+
+type session struct {
+	SessionID string    `json:"session_id"`
+	Status    string    `json:"status"`
+	Date      time.Time `json:"date"`
+	StartedAt time.Time `json:"started_at"`
+
+	Sections []*section `json:"sections"`
+}
+
+type section struct {
+	Name        string       `json:"name"`
+	SoldTickets int          `json:"sold_tickets"`
+	Allotments  []*allotment `json:"allotments"`
+}
+
+type allotment struct {
+	Name          string `json:"name"`
+	IssuedTickets int    `json:"issued_tickets"`
+}
+
+type salePhase struct {
+	Name        string `json:"name"`
+	SoldTickets int    `json:"sold_tickets"`
+}
+
+type refundPolicy struct {
+	DeadlineHours int     `json:"deadline_hours"`
+	Percentage    float64 `json:"percentage"`
+}
+
+const eventStatusOnSale = "on_sale"
+const eventStatusRunning = "running"
 
 // *****+************************************ //
 
@@ -25,11 +86,56 @@ const index = "eventID~section~ticketID"
 
 const Name = "cc-ticket"
 
+// ticketSecretsCollection is the private data collection where
+// the secrets used to generate the rotating validation codes are
+// stored. It must be defined in the chaincode collections config
+const ticketSecretsCollection = "ticketSecrets"
+
+// ticketSecretTransientKey is the key of the transient map used
+// to send the ticket secret, so it is never written in the tx
+const ticketSecretTransientKey = "ticket_secret"
+
+type TicketStatus string
+
+const (
+	// TicketStatusIssued is the status of a ticket
+	// that was sold and is not yet used
+	TicketStatusIssued TicketStatus = "issued"
+
+	// TicketStatusScanned is the status of a ticket
+	// that was used to enter the event
+	TicketStatusScanned TicketStatus = "scanned"
+
+	// TicketStatusVoided is the status of a ticket that
+	// was invalidated and can no longer be used
+	TicketStatusVoided TicketStatus = "voided"
+)
+
+type TicketType string
+
+const (
+	// TicketTypeStandard is the type of the
+	// tickets sold in the public sale
+	TicketTypeStandard TicketType = "standard"
+
+	// TicketTypeComp is the type of the complimentary
+	// tickets issued from an allotment, which are free
+	TicketTypeComp TicketType = "comp"
+
+	// TicketTypePass is the type of the tickets that give
+	// access to one event of a series as part of a pass
+	TicketTypePass TicketType = "pass"
+)
+
 type Ticket struct {
 	TicketID string `json:"ticket_id"`
 	EventID  string `json:"event_id"`
 	Section  string `json:"section"`
 
+	// session of the event for which the ticket
+	// was sold, empty for events without sessions
+	SessionID string `json:"session_id"`
+
 	// represents the public blockchain
 	// token ID
 	TokenID string `json:"token_id"`
@@ -37,57 +143,114 @@ type Ticket struct {
 	// represents the owner id
 	// in the web service database
 	OwnerID string `json:"owner"`
+
+	Type TicketType `json:"type"`
+
+	// allotment from which the ticket was
+	// issued, only present on comp tickets
+	Allotment string `json:"allotment"`
+
+	// sale phase of the event in which the ticket
+	// was sold, empty if the event has no phases
+	SalePhase string `json:"sale_phase"`
+
+	// pass to which the ticket belongs,
+	// only present on pass tickets
+	PassID string `json:"pass_id"`
+
+	// price charged by cc-event
+	// when the ticket was sold
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency"`
+
+	// reference to the off-chain payment
+	// used to pay the ticket
+	PaymentProvider  string `json:"payment_provider"`
+	PaymentReference string `json:"payment_reference"`
+
+	PurchasedAt time.Time `json:"purchased_at"`
+
+	Status TicketStatus `json:"status"`
+
+	// admission information, only
+	// present once the ticket is scanned
+	ScannedAt   time.Time `json:"scanned_at"`
+	ScannedGate string    `json:"scanned_gate"`
+	ScannedBy   string    `json:"scanned_by"`
+
+	// void information, only
+	// present once the ticket is voided
+	VoidedAt   time.Time `json:"voided_at"`
+	VoidedBy   string    `json:"voided_by"`
+	VoidReason string    `json:"void_reason"`
+
+	// true if the seat was returned to
+	// the section inventory when voided
+	SeatReleased bool `json:"seat_released"`
+}
+
+// ticketSale is the response of the
+// "SellTicket" function of cc-event
+type ticketSale struct {
+	EventID   string  `json:"event_id"`
+	SalePhase string  `json:"sale_phase"`
+	Price     float64 `json:"price"`
+
+	// offers made to waitlisted owners, that
+	// must be emitted by this chaincode
+	WaitlistOffers []json.RawMessage `json:"waitlist_offers"`
 }
 
 // Issue a new ticket for the event with ID "eventID" in the section "section"
 // to the owner with ID "ownerID". This method will call the "cc-event" chaincode
 // to check if the event is "on sale" or the section has remaining tickets.
+// Tickets of events with sessions are issued for the session "sessionID".
+// The secret used to generate the rotating validation codes of the ticket must
+// be sent in the transient map with key "ticket_secret", and it is stored in
+// a private data collection
 //
 // Params
 // * - ticketID -> uuid format
 // * - eventID  -> uuid format
+// * - sessionID -> uuid format (must be empty for events without sessions)
 // * - section  -> string (must be equal to the section name of the event)
 // * - ownerID  -> uuid format
 // * - tokenID  -> hexadecimal string representing the tokenID of the public blockchain (uint256)
+// * - currency -> ISO 4217 code of the currency used to pay the ticket
+// * - paymentProvider  -> name of the payment processor
+// * - paymentReference -> id of the payment in the payment processor
+// * - merkleProof -> hex encoded proof that the owner is in the current sale phase allowlist (can be empty)
 //
 // The return value can be:
 //   - - the ticket created serialized in JSON format
 //   - - error in case some conditions to issue the ticket are not fulfilled
 //     such as the event is not on sale or the section has not more remaining tickets
-func (c *Contract) Issue(ctx common.ITickenTxContext, ticketID, eventID, section, ownerID, tokenID string) (*Ticket, error) {
-	existentTicket, err := c.GetTicket(ctx, ticketID)
-	if existentTicket != nil {
-		return nil, ccErr("ticket with ID %s already exists", ticketID)
+func (c *Contract) Issue(ctx common.ITickenTxContext, ticketID, eventID, sessionID, section, ownerID, tokenID, currency, paymentProvider, paymentReference string, merkleProof []string) (*Ticket, error) {
+	if len(currency) == 0 {
+		return nil, ccErr("currency is required")
 	}
-
-	ownerIDParsed, err := uuid.Parse(ownerID)
-	if err != nil {
-		return nil, ccErr("error parsing owner id: %v", err)
+	if len(paymentReference) == 0 {
+		return nil, ccErr("payment reference is required")
 	}
-	eventIDParsed, err := uuid.Parse(eventID)
+
+	ticket, err := c.newTicket(ctx, ticketID, eventID, section, ownerID, tokenID)
 	if err != nil {
-		return nil, ccErr("error parsing event id: %v", err)
+		return nil, err // this error is already formatted
 	}
-	ticketIDParsed, err := uuid.Parse(ticketID)
+
+	ticketSecret, err := getTicketSecret(ctx)
 	if err != nil {
-		return nil, ccErr("error parsing ticket id: %v", err)
-	}
-	tokenIDParsed, ok := new(big.Int).SetString(tokenID, 16)
-	if !ok {
-		return nil, ccErr("token ID is not a valid uint256")
+		return nil, err // this error is already formatted
 	}
 
-	ticket := Ticket{
-		TicketID: ticketIDParsed.String(),
-		EventID:  eventIDParsed.String(),
-		Section:  section,
-		TokenID:  tokenIDParsed.Text(16),
-		OwnerID:  ownerIDParsed.String(),
-	}
+	ticket.SessionID = sessionID
+	ticket.Type = TicketTypeStandard
+	ticket.Currency = strings.ToUpper(currency)
+	ticket.PaymentProvider = paymentProvider
+	ticket.PaymentReference = paymentReference
 
-	ticketJSON, err := json.Marshal(ticket)
-	if err != nil {
-		return nil, ccErr("failed to serialize ticket: %v", err)
+	if err := verifyAllowlist(ctx, ticket.EventID, ticket.Section, ticket.OwnerID, merkleProof); err != nil {
+		return nil, err // this error is already formatted
 	}
 
 	// add ticket into the chaincode cc-event
@@ -96,28 +259,37 @@ func (c *Contract) Issue(ctx common.ITickenTxContext, ticketID, eventID, section
 	// count are updated simultaneously in the same tx
 	ccEventSellTicketResponse := ctx.GetStub().InvokeChaincode(
 		ccEventName,
-		getCCCallArgs(ccEventSellTicketFunc, eventID, section),
+		getCCCallArgs(ccEventSellTicketFunc, eventID, sessionID, section, ticket.OwnerID),
 		ctx.GetStub().GetChannelID(),
 	)
 
 	if ccEventSellTicketResponse.Status != shim.OK {
-		return nil, ccErr(ccEventSellTicketResponse.Message)
+		return nil, ccErr("%s", ccEventSellTicketResponse.Message)
 	}
 
-	//  Create an index to enable section-based range queries, e.g. return all tickets from section V.I.P.
-	//  An 'index' is a normal key-value entry in the ledger.
-	//  The key is a composite key, with the elements that you want to range query on listed first.
-	//  This will enable very efficient state range queries based on composite keys matching indexName~section~*
-	sectionIndexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{eventID, ticket.Section, ticket.TicketID})
-	if err != nil {
-		return nil, ccErr("failed to create section index key: %v", err)
+	var sale ticketSale
+	if err := json.Unmarshal(ccEventSellTicketResponse.Payload, &sale); err != nil {
+		return nil, ccErr("failed to deserialize ticket sale: %v", err)
+	}
+
+	// the price is decided by cc-event, so the
+	// ticket keeps a record of what was charged
+	ticket.Price = sale.Price
+	ticket.SalePhase = sale.SalePhase
+
+	if err := putTicket(ctx, ticket); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := putTicketSecret(ctx, ticket.TicketID, ticketSecret); err != nil {
+		return nil, err // this error is already formatted
 	}
 
-	if err := ctx.GetStub().PutState(sectionIndexKey, ticketJSON); err != nil {
-		return nil, ccErr("failed to updated the state: %v", err)
+	if err := common.EmitWaitlistOffers(ctx, sale.WaitlistOffers); err != nil {
+		return nil, ccErr("failed to emit waitlist offers: %v", err)
 	}
 
-	return &ticket, nil
+	return ticket, nil
 }
 
 // GetTicket returns the ticket information of the event with id "ticketID".
@@ -137,7 +309,7 @@ func (c *Contract) GetTicket(ctx common.ITickenTxContext, ticketID string) (*Tic
 	}
 
 	var ticket Ticket
-	if err := json.Unmarshal(ticketJSON, &ticket); err != nil {
+	if _, err := ticketSchema.Unmarshal(ticketJSON, &ticket); err != nil {
 		return nil, ccErr("failed to deserialize ticket: %v", err)
 	}
 
@@ -162,7 +334,126 @@ func (c *Contract) GetSectionTickets(ctx common.ITickenTxContext, eventID, secti
 	}
 	defer sectionTicketsIterator.Close()
 
-	return constructQueryResponseFromIterator(sectionTicketsIterator)
+	return constructQueryResponseFromIterator(ctx, sectionTicketsIterator)
+}
+
+// newTicket parses the identifiers of a new ticket and returns
+// it with status "issued" and the transaction time as purchase
+// time. It fails if a ticket with the same ID already exists
+func (c *Contract) newTicket(ctx common.ITickenTxContext, ticketID, eventID, section, ownerID, tokenID string) (*Ticket, error) {
+	existentTicket, _ := c.GetTicket(ctx, ticketID)
+	if existentTicket != nil {
+		return nil, ccErr("ticket with ID %s already exists", ticketID)
+	}
+
+	ownerIDParsed, err := uuid.Parse(ownerID)
+	if err != nil {
+		return nil, ccErr("error parsing owner id: %v", err)
+	}
+	eventIDParsed, err := uuid.Parse(eventID)
+	if err != nil {
+		return nil, ccErr("error parsing event id: %v", err)
+	}
+	ticketIDParsed, err := uuid.Parse(ticketID)
+	if err != nil {
+		return nil, ccErr("error parsing ticket id: %v", err)
+	}
+	tokenIDParsed, ok := new(big.Int).SetString(tokenID, 16)
+	if !ok {
+		return nil, ccErr("token ID is not a valid uint256")
+	}
+
+	purchasedAt, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	return &Ticket{
+		TicketID: ticketIDParsed.String(),
+		EventID:  eventIDParsed.String(),
+		Section:  section,
+		TokenID:  tokenIDParsed.Text(16),
+		OwnerID:  ownerIDParsed.String(),
+
+		PurchasedAt: purchasedAt,
+
+		Status: TicketStatusIssued,
+	}, nil
+}
+
+// getTicketSecret reads the secret of the new
+// ticket sent in the transient map
+func getTicketSecret(ctx common.ITickenTxContext) ([]byte, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, ccErr("failed to read transient map: %v", err)
+	}
+
+	ticketSecret, ok := transientMap[ticketSecretTransientKey]
+	if !ok || len(ticketSecret) == 0 {
+		return nil, ccErr("ticket secret is required in the transient map")
+	}
+
+	return ticketSecret, nil
+}
+
+func putTicketSecret(ctx common.ITickenTxContext, ticketID string, ticketSecret []byte) error {
+	if err := ctx.GetStub().PutPrivateData(ticketSecretsCollection, ticketID, ticketSecret); err != nil {
+		return ccErr("failed to store ticket secret: %v", err)
+	}
+	return nil
+}
+
+// putTicket stores the ticket under its ID and adds it to
+// the section index
+func putTicket(ctx common.ITickenTxContext, ticket *Ticket) error {
+	ticketJSON, err := ticketSchema.Marshal(ticket)
+	if err != nil {
+		return ccErr("failed to serialize ticket: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(ticket.TicketID, ticketJSON); err != nil {
+		return ccErr("failed to updated the state: %v", err)
+	}
+
+	//  Create an index to enable section-based range queries, e.g. return all tickets from section V.I.P.
+	//  An 'index' is a normal key-value entry in the ledger.
+	//  The key is a composite key, with the elements that you want to range query on listed first.
+	//  This will enable very efficient state range queries based on composite keys matching indexName~section~*
+	//  Only the key is needed, so the value is a null character like in the fabric samples
+	sectionIndexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{ticket.EventID, ticket.Section, ticket.TicketID})
+	if err != nil {
+		return ccErr("failed to create section index key: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(sectionIndexKey, []byte{0x00}); err != nil {
+		return ccErr("failed to updated the state: %v", err)
+	}
+
+	return nil
+}
+
+// isPlatformAdmin tells if the identity that submitted
+// the transaction is a platform admin
+func isPlatformAdmin(ctx common.ITickenTxContext) (bool, error) {
+	isAdmin, err := ctx.IsPlatformAdmin()
+	if err != nil {
+		return false, ccErr("could not get context identity: %v", err)
+	}
+	return isAdmin, nil
+}
+
+// checkPlatformAdmin fails if the identity that submitted
+// the transaction is not a platform admin
+func checkPlatformAdmin(ctx common.ITickenTxContext) error {
+	isAdmin, err := isPlatformAdmin(ctx)
+	if err != nil {
+		return err // this error is already formatted
+	}
+	if !isAdmin {
+		return ccErr("only platform admins can perform this operation")
+	}
+	return nil
 }
 
 func ccErr(format string, args ...any) error {
@@ -181,8 +472,10 @@ func getCCCallArgs(opName string, args ...string) [][]byte {
 	return queryArgs
 }
 
-// constructQueryResponseFromIterator constructs a slice of assets from the resultsIterator
-func constructQueryResponseFromIterator(resultsIterator shim.StateQueryIteratorInterface) ([]*Ticket, error) {
+// constructQueryResponseFromIterator constructs a slice of tickets from the
+// section index iterator. Tickets issued before they were stored under their
+// own ID have the whole ticket as the index value, so it is used directly
+func constructQueryResponseFromIterator(ctx common.ITickenTxContext, resultsIterator shim.StateQueryIteratorInterface) ([]*Ticket, error) {
 	var assets []*Ticket
 
 	for resultsIterator.HasNext() {
@@ -190,9 +483,21 @@ func constructQueryResponseFromIterator(resultsIterator shim.StateQueryIteratorI
 		if err != nil {
 			return nil, err
 		}
-		var asset Ticket
 
-		if err := json.Unmarshal(queryResult.Value, &asset); err != nil {
+		ticketJSON := queryResult.Value
+		if len(ticketJSON) == 1 && ticketJSON[0] == 0x00 {
+			_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResult.Key)
+			if err != nil {
+				return nil, err
+			}
+			ticketJSON, err = ctx.GetStub().GetState(keyParts[2])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var asset Ticket
+		if _, err := ticketSchema.Unmarshal(ticketJSON, &asset); err != nil {
 			return nil, err
 		}
 		assets = append(assets, &asset)
@@ -0,0 +1,96 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+)
+
+// IssueComp issues a complimentary ticket for the owner with ID "ownerID"
+// from the allotment "allotment" of the section "section". This method
+// calls cc-event to draw the seat from the allotment, so the caller must
+// be the allotment assignee or the event organizer. Comp tickets are
+// free and have type "comp". As in Issue, the ticket secret must be sent
+// in the transient map with key "ticket_secret"
+//
+// Params
+// * - ticketID  -> uuid format
+// * - eventID   -> uuid format
+// * - sessionID -> uuid format (must be empty for events without sessions)
+// * - section   -> string (must be equal to the section name of the event)
+// * - allotment -> string (must be equal to the allotment name of the section)
+// * - ownerID   -> uuid format
+// * - tokenID   -> hexadecimal string representing the tokenID of the public blockchain (uint256)
+//
+// The return value can be:
+// * - the ticket created serialized in JSON format
+// * - error in case the allotment has no tickets left or the caller is not allowed to use it
+func (c *Contract) IssueComp(ctx common.ITickenTxContext, ticketID, eventID, sessionID, section, allotment, ownerID, tokenID string) (*Ticket, error) {
+	ticket, err := c.newTicket(ctx, ticketID, eventID, section, ownerID, tokenID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	ticketSecret, err := getTicketSecret(ctx)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	ticket.SessionID = sessionID
+	ticket.Type = TicketTypeComp
+	ticket.Allotment = allotment
+
+	saleJSON, err := ctx.GetInvoker(ccEventName).Invoke(ccEventIssueAllotmentTicketFunc, ticket.EventID, ticket.SessionID, ticket.Section, allotment)
+	if err != nil {
+		return nil, ccErr("%s", err)
+	}
+
+	var sale ticketSale
+	if err := json.Unmarshal(saleJSON, &sale); err != nil {
+		return nil, ccErr("failed to deserialize ticket sale: %v", err)
+	}
+	ticket.Price = sale.Price
+
+	if err := putTicket(ctx, ticket); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := putTicketSecret(ctx, ticket.TicketID, ticketSecret); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return ticket, nil
+}
+
+// GetAllotmentTickets returns the comp tickets issued from the
+// allotment "allotment" of the section "section" of the event
+// with ID "eventID", including the voided ones
+//
+// Params
+// * - eventID   -> uuid format
+// * - section   -> string (must be equal to the section name of the event)
+// * - allotment -> string (must be equal to the allotment name of the section)
+//
+// The return value can be:
+// * - the list of tickets (possibly empty)
+// * - error in case the tickets could not be read
+func (c *Contract) GetAllotmentTickets(ctx common.ITickenTxContext, eventID, section, allotment string) ([]*Ticket, error) {
+	ticketsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{eventID, section})
+	if err != nil {
+		return nil, ccErr("failed to create a ticket iterator: %v", err)
+	}
+	defer ticketsIterator.Close()
+
+	tickets, err := constructQueryResponseFromIterator(ctx, ticketsIterator)
+	if err != nil {
+		return nil, ccErr("failed to read tickets: %v", err)
+	}
+
+	allotmentTickets := make([]*Ticket, 0)
+	for _, ticket := range tickets {
+		if ticket.Type == TicketTypeComp && ticket.Allotment == allotment {
+			allotmentTickets = append(allotmentTickets, ticket)
+		}
+	}
+
+	return allotmentTickets, nil
+}
@@ -0,0 +1,88 @@
+package contract
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"testing"
+)
+
+// testIdentity is the identity that
+// submits the transactions of the tests
+type testIdentity struct {
+	mspID    string
+	username string
+
+	// value of the role attribute,
+	// empty if the identity has none
+	role string
+}
+
+var (
+	organizerIdentity     = testIdentity{mspID: "Org1MSP", username: "org1"}
+	adminIdentity         = testIdentity{mspID: "AdminMSP", username: "admin", role: common.RoleAdmin}
+	ticketServiceIdentity = testIdentity{mspID: "ServiceMSP", username: "service", role: common.RoleTicketService}
+)
+
+func (identity testIdentity) GetID() (string, error)    { return identity.username, nil }
+func (identity testIdentity) GetMSPID() (string, error) { return identity.mspID, nil }
+
+func (identity testIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	if attrName == common.RoleAttribute && len(identity.role) > 0 {
+		return identity.role, true, nil
+	}
+	return "", false, nil
+}
+
+func (identity testIdentity) AssertAttributeValue(attrName, attrValue string) error { return nil }
+
+// the username of the identities is
+// read from their organizational unit
+func (identity testIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: []string{identity.username}}}, nil
+}
+
+func newTestContext(stub *shimtest.MockStub, identity testIdentity) common.ITickenTxContext {
+	ctx := common.NewTransactionContext()
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+	return ctx
+}
+
+// eventContract replaces cc-event,
+// returning always the same event
+type eventContract struct {
+	contractapi.Contract
+	event *event
+}
+
+func (c *eventContract) GetEvent(eventID string) (string, error) {
+	eventJSON, err := json.Marshal(c.event)
+	return string(eventJSON), err
+}
+
+// newTestStub returns a stub that reads "ev" from cc-event
+// and has the tickets stored in a previous transaction
+func newTestStub(t *testing.T, ev *event, tickets ...*Ticket) *shimtest.MockStub {
+	ccEvent, err := contractapi.NewChaincode(&eventContract{event: ev})
+	if err != nil {
+		t.Fatalf("failed to create cc-event: %v", err)
+	}
+
+	stub := shimtest.NewMockStub(Name, nil)
+	stub.MockPeerChaincode(ccEventName, shimtest.NewMockStub(ccEventName, ccEvent), "")
+
+	stub.MockTransactionStart("setup")
+	defer stub.MockTransactionEnd("setup")
+
+	for _, ticket := range tickets {
+		if err := putTicket(newTestContext(stub, adminIdentity), ticket); err != nil {
+			t.Fatalf("putTicket() error = %v", err)
+		}
+	}
+
+	return stub
+}
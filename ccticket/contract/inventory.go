@@ -0,0 +1,382 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"sort"
+)
+
+// SectionInventory compares the ticket count of a section kept
+// by cc-event with the tickets stored in cc-ticket
+type SectionInventory struct {
+	Section string `json:"section"`
+
+	// session of the section, empty
+	// for events without sessions
+	Session string `json:"session"`
+
+	// ticket count of the section in cc-event
+	SoldTickets int `json:"sold_tickets"`
+
+	// tickets of the section stored in cc-ticket that
+	// hold a seat (voided tickets whose seat was
+	// released are not included)
+	IssuedTickets int `json:"issued_tickets"`
+
+	// IssuedTickets - SoldTickets
+	Difference int `json:"difference"`
+
+	// false if the section has tickets in cc-ticket
+	// but it does not exist in the event
+	InEvent bool `json:"in_event"`
+
+	Allotments []*AllotmentInventory `json:"allotments"`
+}
+
+// AllotmentInventory compares the tickets issued from an allotment
+// of a section kept by cc-event with the tickets stored in cc-ticket
+type AllotmentInventory struct {
+	Allotment string `json:"allotment"`
+
+	// tickets issued from the allotment in cc-event
+	SoldTickets int `json:"sold_tickets"`
+
+	// comp tickets of the allotment stored in cc-ticket
+	// that hold a seat (voided tickets whose seat was
+	// released are not included)
+	IssuedTickets int `json:"issued_tickets"`
+
+	// IssuedTickets - SoldTickets
+	Difference int `json:"difference"`
+
+	// false if the allotment has tickets in cc-ticket
+	// but it does not exist in the section
+	InEvent bool `json:"in_event"`
+}
+
+// SalePhaseInventory compares the tickets sold during a sale phase
+// kept by cc-event with the tickets stored in cc-ticket
+type SalePhaseInventory struct {
+	SalePhase string `json:"sale_phase"`
+
+	// tickets sold during the phase in cc-event
+	SoldTickets int `json:"sold_tickets"`
+
+	// tickets sold during the phase stored in cc-ticket,
+	// including the voided ones, because the phases
+	// don't get back the seats that are released
+	IssuedTickets int `json:"issued_tickets"`
+
+	// IssuedTickets - SoldTickets
+	Difference int `json:"difference"`
+}
+
+// InventoryReport is the result of reconciling the ticket
+// counts of an event between cc-event and cc-ticket
+type InventoryReport struct {
+	EventID    string                `json:"event_id"`
+	Consistent bool                  `json:"consistent"`
+	Sections   []*SectionInventory   `json:"sections"`
+	SalePhases []*SalePhaseInventory `json:"sale_phases"`
+
+	// tickets sold in the public sale of an event with sale
+	// phases that don't record their phase, because they
+	// were sold before it was recorded. While there are
+	// some, the sale phases counters can not be repaired
+	UnknownPhaseTickets int `json:"unknown_phase_tickets"`
+}
+
+// GetInventoryReport compares the ticket count of every section of the
+// event with ID "eventID" stored in cc-event with the tickets stored in
+// cc-ticket, and reports the sections where they don't match. A ticket
+// holds a seat unless it was voided and its seat was released. For
+// events with sessions, the sections of every session are reported.
+// The tickets issued from each allotment and the tickets sold during
+// each sale phase are compared as well
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the report of each section serialized in JSON format
+// * - error in case the event or the tickets could not be read
+func (c *Contract) GetInventoryReport(ctx common.ITickenTxContext, eventID string) (*InventoryReport, error) {
+	return getInventoryReport(ctx, eventID)
+}
+
+// RepairInventory overwrites the ticket count in cc-event of every
+// section of the event with ID "eventID" that does not match the
+// tickets stored in cc-ticket, which are taken as the source of truth,
+// together with the tickets issued from its allotments and the tickets
+// sold during its sale phases. Sections and allotments that only exist
+// in cc-ticket can not be repaired and are still reported, and so are
+// the sale phases while some tickets don't record their phase. The
+// caller must be a platform admin
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the report before the repair serialized in JSON format
+// * - error in case the caller is not an admin or a counter can not be updated
+func (c *Contract) RepairInventory(ctx common.ITickenTxContext, eventID string) (*InventoryReport, error) {
+	if err := checkPlatformAdmin(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	report, err := getInventoryReport(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	// all the counters are sent in one call, because each
+	// call writes the whole event and only the last one of
+	// the transaction would be kept
+	corrections := common.InventoryCorrection{
+		Sections:   make([]*common.SoldTicketsCorrection, 0),
+		SalePhases: make([]*common.SalePhaseCorrection, 0),
+	}
+
+	for _, inventory := range report.Sections {
+		if !inventory.InEvent {
+			continue
+		}
+
+		allotmentCorrections := make([]*common.AllotmentCorrection, 0)
+		for _, allotmentInventory := range inventory.Allotments {
+			if allotmentInventory.Difference == 0 || !allotmentInventory.InEvent {
+				continue
+			}
+			allotmentCorrections = append(allotmentCorrections, &common.AllotmentCorrection{
+				Name:          allotmentInventory.Allotment,
+				IssuedTickets: allotmentInventory.IssuedTickets,
+			})
+		}
+
+		if inventory.Difference == 0 && len(allotmentCorrections) == 0 {
+			continue
+		}
+
+		corrections.Sections = append(corrections.Sections, &common.SoldTicketsCorrection{
+			Session:     inventory.Session,
+			Section:     inventory.Section,
+			SoldTickets: inventory.IssuedTickets,
+			Allotments:  allotmentCorrections,
+		})
+	}
+
+	if report.UnknownPhaseTickets == 0 {
+		for _, inventory := range report.SalePhases {
+			if inventory.Difference == 0 {
+				continue
+			}
+			corrections.SalePhases = append(corrections.SalePhases, &common.SalePhaseCorrection{
+				Name:        inventory.SalePhase,
+				SoldTickets: inventory.IssuedTickets,
+			})
+		}
+	}
+
+	if len(corrections.Sections) == 0 && len(corrections.SalePhases) == 0 {
+		return report, nil
+	}
+
+	correctionsJSON, err := json.Marshal(corrections)
+	if err != nil {
+		return nil, ccErr("failed to serialize corrections: %v", err)
+	}
+
+	_, err = ctx.GetInvoker(ccEventName).Invoke(ccEventSetSoldTicketsBatchFunc, eventID, string(correctionsJSON))
+	if err != nil {
+		return nil, ccErr("%s", err)
+	}
+
+	return report, nil
+}
+
+// sectionKey identifies a section
+// of a session of the event
+type sectionKey struct {
+	session string
+	section string
+}
+
+// allotmentKey identifies an allotment
+// of a section of the event
+type allotmentKey struct {
+	sectionKey
+	allotment string
+}
+
+func getInventoryReport(ctx common.ITickenTxContext, eventID string) (*InventoryReport, error) {
+	ev, err := getEvent(ctx, eventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	ticketsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{eventID})
+	if err != nil {
+		return nil, ccErr("failed to create a ticket iterator: %v", err)
+	}
+	defer ticketsIterator.Close()
+
+	tickets, err := constructQueryResponseFromIterator(ctx, ticketsIterator)
+	if err != nil {
+		return nil, ccErr("failed to read tickets: %v", err)
+	}
+
+	report := InventoryReport{
+		EventID:    eventID,
+		Consistent: true,
+		Sections:   make([]*SectionInventory, 0),
+		SalePhases: make([]*SalePhaseInventory, 0),
+	}
+
+	issuedTickets := make(map[sectionKey]int)
+	allotmentTickets := make(map[allotmentKey]int)
+	phaseTickets := make(map[string]int)
+	for _, ticket := range tickets {
+		// phases are only added while the event is in draft,
+		// so every public ticket of an event with phases was
+		// sold during one of them
+		if len(ticket.SalePhase) > 0 {
+			phaseTickets[ticket.SalePhase] += 1
+		} else if len(ticket.Allotment) == 0 && len(ev.SalePhases) > 0 {
+			report.UnknownPhaseTickets += 1
+		}
+
+		if ticket.Status == TicketStatusVoided && ticket.SeatReleased {
+			continue
+		}
+
+		key := sectionKey{ticket.SessionID, ticket.Section}
+		issuedTickets[key] += 1
+		if len(ticket.Allotment) > 0 {
+			allotmentTickets[allotmentKey{key, ticket.Allotment}] += 1
+		}
+	}
+
+	// the tickets of events with sessions
+	// are counted in the session sections
+	eventSections := map[string][]*section{"": ev.Sections}
+	sessionIDs := []string{""}
+	if len(ev.Sessions) > 0 {
+		eventSections = make(map[string][]*section)
+		sessionIDs = make([]string, 0)
+		for _, s := range ev.Sessions {
+			eventSections[s.SessionID] = s.Sections
+			sessionIDs = append(sessionIDs, s.SessionID)
+		}
+	}
+
+	// the sections of the event are reported in
+	// their order, followed by the sections that
+	// only exist in cc-ticket sorted by name
+	for _, sessionID := range sessionIDs {
+		for _, s := range eventSections[sessionID] {
+			key := sectionKey{sessionID, s.Name}
+			report.Sections = append(report.Sections, &SectionInventory{
+				Section:       s.Name,
+				Session:       sessionID,
+				SoldTickets:   s.SoldTickets,
+				IssuedTickets: issuedTickets[key],
+				Difference:    issuedTickets[key] - s.SoldTickets,
+				InEvent:       true,
+				Allotments:    getAllotmentsInventory(key, s.Allotments, allotmentTickets),
+			})
+			delete(issuedTickets, key)
+		}
+	}
+
+	unknownSections := make([]sectionKey, 0)
+	for key := range issuedTickets {
+		unknownSections = append(unknownSections, key)
+	}
+	sort.Slice(unknownSections, func(i, j int) bool {
+		if unknownSections[i].session != unknownSections[j].session {
+			return unknownSections[i].session < unknownSections[j].session
+		}
+		return unknownSections[i].section < unknownSections[j].section
+	})
+
+	for _, key := range unknownSections {
+		report.Sections = append(report.Sections, &SectionInventory{
+			Section:       key.section,
+			Session:       key.session,
+			IssuedTickets: issuedTickets[key],
+			Difference:    issuedTickets[key],
+			InEvent:       false,
+			Allotments:    getAllotmentsInventory(key, nil, allotmentTickets),
+		})
+	}
+
+	for _, phase := range ev.SalePhases {
+		report.SalePhases = append(report.SalePhases, &SalePhaseInventory{
+			SalePhase:     phase.Name,
+			SoldTickets:   phase.SoldTickets,
+			IssuedTickets: phaseTickets[phase.Name],
+			Difference:    phaseTickets[phase.Name] - phase.SoldTickets,
+		})
+	}
+
+	for _, inventory := range report.Sections {
+		if inventory.Difference != 0 || !inventory.InEvent {
+			report.Consistent = false
+		}
+		for _, allotmentInventory := range inventory.Allotments {
+			if allotmentInventory.Difference != 0 || !allotmentInventory.InEvent {
+				report.Consistent = false
+			}
+		}
+	}
+
+	for _, inventory := range report.SalePhases {
+		if inventory.Difference != 0 {
+			report.Consistent = false
+		}
+	}
+	if report.UnknownPhaseTickets > 0 {
+		report.Consistent = false
+	}
+
+	return &report, nil
+}
+
+// getAllotmentsInventory compares the allotments of the section "key"
+// with the tickets issued from them. The allotments are reported in
+// their order, followed by the ones that only exist in cc-ticket
+// sorted by name
+func getAllotmentsInventory(key sectionKey, allotments []*allotment, allotmentTickets map[allotmentKey]int) []*AllotmentInventory {
+	inventories := make([]*AllotmentInventory, 0)
+	for _, a := range allotments {
+		issued := allotmentTickets[allotmentKey{key, a.Name}]
+		inventories = append(inventories, &AllotmentInventory{
+			Allotment:     a.Name,
+			SoldTickets:   a.IssuedTickets,
+			IssuedTickets: issued,
+			Difference:    issued - a.IssuedTickets,
+			InEvent:       true,
+		})
+		delete(allotmentTickets, allotmentKey{key, a.Name})
+	}
+
+	unknownAllotments := make([]string, 0)
+	for k := range allotmentTickets {
+		if k.sectionKey == key {
+			unknownAllotments = append(unknownAllotments, k.allotment)
+		}
+	}
+	sort.Strings(unknownAllotments)
+
+	for _, name := range unknownAllotments {
+		issued := allotmentTickets[allotmentKey{key, name}]
+		inventories = append(inventories, &AllotmentInventory{
+			Allotment:     name,
+			IssuedTickets: issued,
+			Difference:    issued,
+			InEvent:       false,
+		})
+	}
+
+	return inventories
+}
@@ -0,0 +1,142 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+	"strings"
+)
+
+// ticketSchema is the schema of the tickets stored in the ledger. When
+// the Ticket struct changes in a way that old records can not be read,
+// its version must be increased and an upgrade must be registered
+var ticketSchema = common.NewSchema("ticket", 1).
+	RegisterUpgrade(0, upgradeTicketV0)
+
+// Migrate rewrites with the current schema version the tickets stored
+// with an older one. At most "pageSize" tickets are read in each call,
+// starting from "bookmark", and the bookmark of the next page is
+// returned, so the whole ledger can be migrated in many transactions.
+// The tickets stored under their ID are migrated first, and then the
+// section index is read to move the tickets issued before they were
+// stored under their ID, which only exist as the value of their index
+// entry. Tickets are also upgraded when they are read, so the migration
+// can run while the channel is live. The caller must be a platform admin
+//
+// Params
+// * - pageSize -> amount of tickets read in the transaction
+// * - bookmark -> bookmark returned by the previous page (empty for the first one)
+//
+// The return value can be:
+// * - the result of the page serialized in JSON format (empty bookmark on the last page)
+// * - error in case the caller is not an admin or a ticket can not be upgraded
+func (c *Contract) Migrate(ctx common.ITickenTxContext, pageSize, bookmark string) (*common.MigrationPage, error) {
+	if err := checkPlatformAdmin(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	pageSizeParsed, err := strconv.Atoi(pageSize)
+	if err != nil {
+		return nil, ccErr("error converting page size: %v", err)
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{})
+	if err != nil {
+		return nil, ccErr("failed to create section index key: %v", err)
+	}
+
+	// the bookmarks of the section index are composite keys,
+	// which never collide with the simple keys of the tickets
+	if strings.HasPrefix(bookmark, indexKey) {
+		page, err := migrateIndexedTickets(ctx, pageSizeParsed, bookmark)
+		if err != nil {
+			return nil, ccErr("failed to migrate indexed tickets: %v", err)
+		}
+		return page, nil
+	}
+
+	// tickets are the only entity stored under simple
+	// keys, the rest of them use composite keys
+	page, err := ticketSchema.Migrate(ctx, pageSizeParsed, bookmark)
+	if err != nil {
+		return nil, ccErr("failed to migrate tickets: %v", err)
+	}
+
+	// once the simple keys were read, the next
+	// page starts from the beginning of the index
+	if len(page.Bookmark) == 0 {
+		page.Bookmark = indexKey
+	}
+
+	return page, nil
+}
+
+// migrateIndexedTickets reads at most "pageSize" entries of the section
+// index from "bookmark" on. The entries that hold the whole ticket, as
+// they were stored before the tickets had their own key, are moved to
+// the ID of the ticket with the current version, leaving the null
+// character as the value of the index entry
+func migrateIndexedTickets(ctx common.ITickenTxContext, pageSize int, bookmark string) (*common.MigrationPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("invalid page size %d - page size must be greater than 0", pageSize)
+	}
+
+	indexIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a section index iterator: %v", err)
+	}
+	defer indexIterator.Close()
+
+	page := common.MigrationPage{}
+	for indexIterator.HasNext() {
+		entry, err := indexIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read section index: %v", err)
+		}
+
+		// fabric can not start a partial composite key
+		// query from a key, so the previous pages are skipped
+		if entry.Key < bookmark {
+			continue
+		}
+
+		if page.Scanned == pageSize {
+			page.Bookmark = entry.Key
+			break
+		}
+		page.Scanned += 1
+
+		if len(entry.Value) == 1 && entry.Value[0] == 0x00 {
+			continue
+		}
+
+		var ticket Ticket
+		if _, err := ticketSchema.Unmarshal(entry.Value, &ticket); err != nil {
+			return nil, fmt.Errorf("failed to upgrade indexed ticket %s: %v", entry.Key, err)
+		}
+
+		if err := putTicket(ctx, &ticket); err != nil {
+			return nil, err
+		}
+		page.Migrated += 1
+	}
+
+	return &page, nil
+}
+
+// upgradeTicketV0 upgrades the tickets stored before the envelope
+// existed. The tickets issued before the ticket types were added
+// have no type, and all of them were sold in the public sale
+func upgradeTicketV0(data json.RawMessage) (json.RawMessage, error) {
+	var ticket map[string]any
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, err
+	}
+
+	if ticketType, _ := ticket["type"].(string); len(ticketType) == 0 {
+		ticket["type"] = string(TicketTypeStandard)
+	}
+
+	return json.Marshal(ticket)
+}
@@ -0,0 +1,98 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	const eventID = "22222222-2222-2222-2222-222222222222"
+
+	tests := []struct {
+		name string
+
+		// ID of a ticket stored as plain JSON, without the envelope
+		ticketID string
+
+		// true if the ticket is stored only as the value of its
+		// section index entry, as before it had its own key
+		indexOnly bool
+	}{
+		{name: "v0 ticket stored in the section index", ticketID: "33333333-3333-3333-3333-333333333331", indexOnly: true},
+		{name: "v0 ticket stored under its ID", ticketID: "33333333-3333-3333-3333-333333333332"},
+	}
+
+	stub := shimtest.NewMockStub(Name, nil)
+	stub.MockTransactionStart("setup")
+	for _, tt := range tests {
+		ticketJSON, _ := json.Marshal(map[string]any{
+			"ticket_id": tt.ticketID,
+			"event_id":  eventID,
+			"section":   "vip",
+			"status":    string(TicketStatusIssued),
+		})
+
+		indexKey, _ := stub.CreateCompositeKey(index, []string{eventID, "vip", tt.ticketID})
+		if tt.indexOnly {
+			_ = stub.PutState(indexKey, ticketJSON)
+			continue
+		}
+		_ = stub.PutState(tt.ticketID, ticketJSON)
+		_ = stub.PutState(indexKey, []byte{0x00})
+	}
+	stub.MockTransactionEnd("setup")
+
+	// a page of one ticket, so the index is read
+	// in a different transaction than the simple keys
+	bookmark := ""
+	migrated := 0
+	for i := 0; ; i++ {
+		if i > len(tests)*2+1 {
+			t.Fatalf("migration did not finish after %d pages", i)
+		}
+
+		stub.MockTransactionStart("migrate")
+		page, err := new(Contract).Migrate(newTestContext(stub, adminIdentity), "1", bookmark)
+		stub.MockTransactionEnd("migrate")
+		if err != nil {
+			t.Fatalf("Migrate() error = %v", err)
+		}
+
+		migrated += page.Migrated
+		bookmark = page.Bookmark
+		if len(bookmark) == 0 {
+			break
+		}
+	}
+
+	if migrated != len(tests) {
+		t.Errorf("migrated %d tickets, want %d", migrated, len(tests))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var envelope common.Envelope
+			if err := json.Unmarshal(stub.State[tt.ticketID], &envelope); err != nil {
+				t.Fatalf("ticket %s is not stored under its ID: %v", tt.ticketID, err)
+			}
+			if envelope.Version != ticketSchema.Version() {
+				t.Errorf("ticket version = %d, want %d", envelope.Version, ticketSchema.Version())
+			}
+
+			var ticket Ticket
+			if err := json.Unmarshal(envelope.Data, &ticket); err != nil {
+				t.Fatalf("failed to deserialize ticket: %v", err)
+			}
+			if ticket.Type != TicketTypeStandard {
+				t.Errorf("ticket type = %q, want %q", ticket.Type, TicketTypeStandard)
+			}
+
+			indexKey, _ := stub.CreateCompositeKey(index, []string{eventID, "vip", tt.ticketID})
+			if value := stub.State[indexKey]; len(value) != 1 || value[0] != 0x00 {
+				t.Errorf("section index value = %q, want the null character", value)
+			}
+		})
+	}
+}
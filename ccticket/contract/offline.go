@@ -0,0 +1,359 @@
+package contract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const offlineScanIndex = "offlineScan~ticketID~scanID"
+const scanConflictIndex = "scanConflict~eventID~scanID"
+
+type ScanConflictReason string
+
+const (
+	// ScanConflictAlreadyScanned is reported when the ticket
+	// was already admitted by another scan
+	ScanConflictAlreadyScanned ScanConflictReason = "already_scanned"
+
+	// ScanConflictBeforeStart is reported when the ticket was
+	// scanned before the event went to status "running"
+	ScanConflictBeforeStart ScanConflictReason = "before_event_start"
+
+	// ScanConflictUnauthorized is reported when the validator was
+	// not allowed to scan tickets of the event at the gate
+	ScanConflictUnauthorized ScanConflictReason = "unauthorized_validator"
+
+	// ScanConflictTicketNotFound is reported when the
+	// scanned ticket does not exist
+	ScanConflictTicketNotFound ScanConflictReason = "ticket_not_found"
+
+	// ScanConflictTicketVoided is reported when the
+	// scanned ticket was voided
+	ScanConflictTicketVoided ScanConflictReason = "ticket_voided"
+
+	// ScanConflictEventNotFound is reported when the scanned
+	// ticket does not exist and neither does the event of the scan
+	ScanConflictEventNotFound ScanConflictReason = "event_not_found"
+)
+
+// OfflineScan is a ticket scan performed by a validator
+// while the scanner device was disconnected
+type OfflineScan struct {
+	// event admitted at the gate. The conflicts of the
+	// tickets that do not exist are reported on it
+	EventID string `json:"event_id"`
+
+	TicketID          string `json:"ticket_id"`
+	Gate              string `json:"gate"`
+	ScannedAt         string `json:"scanned_at"` // RFC3339 format
+	ValidatorMSPID    string `json:"validator_msp_id"`
+	ValidatorUsername string `json:"validator_username"`
+}
+
+// ScanConflict is an offline scan that could not be
+// applied cleanly and must be reviewed
+type ScanConflict struct {
+	ScanID    string             `json:"scan_id"`
+	EventID   string             `json:"event_id"`
+	TicketID  string             `json:"ticket_id"`
+	Gate      string             `json:"gate"`
+	ScannedAt time.Time          `json:"scanned_at"`
+	ScannedBy string             `json:"scanned_by"`
+	Reason    ScanConflictReason `json:"reason"`
+}
+
+// OfflineScanReport summarizes the result of a
+// batch of offline scans
+type OfflineScanReport struct {
+	// scans that admitted a ticket
+	Applied int `json:"applied"`
+
+	// scans that were already submitted
+	// before and were ignored
+	Duplicated int `json:"duplicated"`
+
+	Conflicts []*ScanConflict `json:"conflicts"`
+}
+
+// SubmitOfflineScans uploads the scans performed by validators while
+// they were offline. The scans are applied from the oldest to the newest:
+// the first scan of a ticket admits it, and any following scan of the same
+// ticket is reported as a conflict. Scans performed before the event went
+// to status "running" admit the ticket but are reported as well. Scans
+// already submitted are ignored, so the same batch can be safely retried.
+// Scans of tickets that do not exist are reported on the event submitted
+// with the scan, while the rest are reported on the event of the ticket.
+// If that event does not exist either, the scan is reported as such and
+// the rest of the batch is still applied.
+// The caller must be the validator of every scan or a platform admin
+//
+// Params
+// * - scans -> list of offline scans
+//
+// The return value can be:
+// * - the report with the conflicts found serialized in JSON format
+// * - error in case a scan is malformed or the caller is not allowed to submit it
+func (c *Contract) SubmitOfflineScans(ctx common.ITickenTxContext, scans []OfflineScan) (*OfflineScanReport, error) {
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return nil, ccErr("could not get context identity: %v", err)
+	}
+
+	isAdmin, err := isPlatformAdmin(ctx)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	scanTimes := make([]time.Time, len(scans))
+	for i, scan := range scans {
+		if !isAdmin && (scan.ValidatorMSPID != mspID || scan.ValidatorUsername != username) {
+			return nil, ccErr("scan %d was performed by another validator", i)
+		}
+
+		if len(scan.EventID) == 0 {
+			return nil, ccErr("scan %d has no event id", i)
+		}
+
+		scanTimes[i], err = time.Parse(time.RFC3339, scan.ScannedAt)
+		if err != nil {
+			return nil, ccErr("error parsing scan %d time: %v", i, err)
+		}
+		scanTimes[i] = scanTimes[i].UTC()
+	}
+
+	// the scans are sorted so the earliest one
+	// admits the ticket on every peer
+	order := make([]int, len(scans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scanTimes[order[a]].Before(scanTimes[order[b]])
+	})
+
+	report := OfflineScanReport{
+		Conflicts: make([]*ScanConflict, 0),
+	}
+
+	// the ledger reads don't reflect the writes of
+	// this same transaction, so the tickets and the
+	// scans processed are tracked in memory
+	tickets := make(map[string]*Ticket)
+	events := make(map[string]*event)
+	unknownEvents := make(map[string]bool)
+	processedScans := make(map[string]bool)
+
+	for _, i := range order {
+		scan := scans[i]
+		scannedAt := scanTimes[i]
+		scannedBy := scan.ValidatorUsername + "@" + scan.ValidatorMSPID
+		scanID := offlineScanID(scan, scannedAt)
+
+		if processedScans[scanID] {
+			report.Duplicated += 1
+			continue
+		}
+		processedScans[scanID] = true
+
+		alreadySubmitted, err := offlineScanExists(ctx, scan.TicketID, scanID)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+		if alreadySubmitted {
+			report.Duplicated += 1
+			continue
+		}
+
+		conflict := &ScanConflict{
+			ScanID:    scanID,
+			TicketID:  scan.TicketID,
+			Gate:      scan.Gate,
+			ScannedAt: scannedAt,
+			ScannedBy: scannedBy,
+		}
+
+		ticket, err := c.getTicketToScan(ctx, scan.TicketID, scan.ValidatorMSPID, scan.ValidatorUsername, scan.Gate, tickets)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+
+		// the conflicts of the tickets that do not
+		// exist are reported on the event of the scan
+		conflict.EventID = scan.EventID
+		if ticket != nil {
+			conflict.TicketID = ticket.TicketID
+			conflict.EventID = ticket.EventID
+		}
+
+		// the event of a scan whose ticket does not exist
+		// may not exist either, and cc-event can not check
+		// the validators of an event that does not exist
+		if ticket == nil && !unknownEvents[scan.EventID] {
+			if _, ok := events[scan.EventID]; !ok {
+				ev, err := getEvent(ctx, scan.EventID)
+				if err != nil {
+					unknownEvents[scan.EventID] = true
+				} else {
+					events[scan.EventID] = ev
+				}
+			}
+		}
+
+		isValidator := false
+		if !unknownEvents[conflict.EventID] {
+			isValidator, err = isEventValidator(ctx, conflict.EventID, scan.ValidatorMSPID, scan.ValidatorUsername, scan.Gate)
+			if err != nil {
+				return nil, err // this error is already formatted
+			}
+		}
+
+		if err := putOfflineScan(ctx, scan.TicketID, scanID); err != nil {
+			return nil, err // this error is already formatted
+		}
+
+		switch {
+		case unknownEvents[conflict.EventID]:
+			conflict.Reason = ScanConflictEventNotFound
+		case !isValidator:
+			conflict.Reason = ScanConflictUnauthorized
+		case ticket == nil:
+			conflict.Reason = ScanConflictTicketNotFound
+		case ticket.Status == TicketStatusScanned:
+			conflict.Reason = ScanConflictAlreadyScanned
+		case ticket.Status == TicketStatusVoided:
+			conflict.Reason = ScanConflictTicketVoided
+		default:
+			ev, ok := events[ticket.EventID]
+			if !ok {
+				ev, err = getEvent(ctx, ticket.EventID)
+				if err != nil {
+					return nil, err // this error is already formatted
+				}
+				events[ticket.EventID] = ev
+			}
+
+			ticket.Status = TicketStatusScanned
+			ticket.ScannedAt = scannedAt
+			ticket.ScannedGate = scan.Gate
+			ticket.ScannedBy = scannedBy
+			if err := putTicket(ctx, ticket); err != nil {
+				return nil, err // this error is already formatted
+			}
+			report.Applied += 1
+
+			startedAt := ev.forSession(ticket.SessionID).StartedAt
+			if startedAt.IsZero() || scannedAt.Before(startedAt) {
+				conflict.Reason = ScanConflictBeforeStart
+			}
+		}
+
+		if len(conflict.Reason) > 0 {
+			if err := putScanConflict(ctx, conflict); err != nil {
+				return nil, err // this error is already formatted
+			}
+			report.Conflicts = append(report.Conflicts, conflict)
+		}
+	}
+
+	return &report, nil
+}
+
+// GetScanConflicts returns the conflicts found while applying
+// the offline scans of the event with ID "eventID"
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the list of conflicts (possibly empty)
+// * - error in case the conflicts could not be read
+func (c *Contract) GetScanConflicts(ctx common.ITickenTxContext, eventID string) ([]*ScanConflict, error) {
+	conflictsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(scanConflictIndex, []string{eventID})
+	if err != nil {
+		return nil, ccErr("failed to create a scan conflicts iterator: %v", err)
+	}
+	defer conflictsIterator.Close()
+
+	conflicts := make([]*ScanConflict, 0)
+	for conflictsIterator.HasNext() {
+		queryResult, err := conflictsIterator.Next()
+		if err != nil {
+			return nil, ccErr("failed to read scan conflict: %v", err)
+		}
+
+		var conflict ScanConflict
+		if err := json.Unmarshal(queryResult.Value, &conflict); err != nil {
+			return nil, ccErr("failed to deserialize scan conflict: %v", err)
+		}
+		conflicts = append(conflicts, &conflict)
+	}
+
+	return conflicts, nil
+}
+
+// offlineScanID identifies a scan by all its values,
+// so the same scan submitted twice has the same ID
+func offlineScanID(scan OfflineScan, scannedAt time.Time) string {
+	hash := sha256.New()
+	for _, value := range []string{
+		scan.TicketID,
+		scan.Gate,
+		strconv.FormatInt(scannedAt.Unix(), 10),
+		scan.ValidatorMSPID,
+		scan.ValidatorUsername,
+	} {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0x00})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func offlineScanExists(ctx common.ITickenTxContext, ticketID, scanID string) (bool, error) {
+	scanKey, err := ctx.GetStub().CreateCompositeKey(offlineScanIndex, []string{ticketID, scanID})
+	if err != nil {
+		return false, ccErr("failed to create offline scan key: %v", err)
+	}
+
+	scanValue, err := ctx.GetStub().GetState(scanKey)
+	if err != nil {
+		return false, ccErr("failed to read offline scan: %v", err)
+	}
+
+	return scanValue != nil, nil
+}
+
+func putOfflineScan(ctx common.ITickenTxContext, ticketID, scanID string) error {
+	scanKey, err := ctx.GetStub().CreateCompositeKey(offlineScanIndex, []string{ticketID, scanID})
+	if err != nil {
+		return ccErr("failed to create offline scan key: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(scanKey, []byte{0x00}); err != nil {
+		return ccErr("failed to updated the state: %v", err)
+	}
+
+	return nil
+}
+
+func putScanConflict(ctx common.ITickenTxContext, conflict *ScanConflict) error {
+	conflictKey, err := ctx.GetStub().CreateCompositeKey(scanConflictIndex, []string{conflict.EventID, conflict.ScanID})
+	if err != nil {
+		return ccErr("failed to create scan conflict key: %v", err)
+	}
+
+	conflictJSON, err := json.Marshal(conflict)
+	if err != nil {
+		return ccErr("failed to serialize scan conflict: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(conflictKey, conflictJSON); err != nil {
+		return ccErr("failed to updated the state: %v", err)
+	}
+
+	return nil
+}
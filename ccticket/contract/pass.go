@@ -0,0 +1,390 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const passIndex = "pass~passID"
+
+// Pass gives access to every event of a series, such as all the
+// days of a festival. It is made of one ticket of type "pass" for
+// each event, so each event can admit the pass once
+type Pass struct {
+	PassID   string `json:"pass_id"`
+	SeriesID string `json:"series_id"`
+	Section  string `json:"section"`
+
+	// represents the public blockchain
+	// token ID
+	TokenID string `json:"token_id"`
+
+	// represents the owner id
+	// in the web service database
+	OwnerID string `json:"owner"`
+
+	// sum of the prices of the
+	// tickets of the pass
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency"`
+
+	// reference to the off-chain payment
+	// used to pay the pass
+	PaymentProvider  string `json:"payment_provider"`
+	PaymentReference string `json:"payment_reference"`
+
+	PurchasedAt time.Time `json:"purchased_at"`
+
+	// tickets of the pass, one per
+	// event in the order of the series
+	TicketIDs []string `json:"ticket_ids"`
+}
+
+// passSeries is the response of the
+// "GetSeries" function of cc-event
+type passSeries struct {
+	SeriesID string   `json:"series_id"`
+	EventIDs []string `json:"event_ids"`
+}
+
+// passSale is the response of the
+// "SellPass" function of cc-event
+type passSale struct {
+	Price          float64           `json:"price"`
+	Sales          []*ticketSale     `json:"sales"`
+	WaitlistOffers []json.RawMessage `json:"waitlist_offers"`
+}
+
+// IssuePass issues a pass for the series with ID "seriesID" in the section
+// "section" to the owner with ID "ownerID". This method calls cc-event to sell
+// a ticket of the section in every event of the series, so the pass is only
+// issued if all of them have a seat. A ticket of type "pass" is created for
+// every event, and the pass is scanned with its own ID. As in Issue, the pass
+// secret must be sent in the transient map with key "ticket_secret"
+//
+// Params
+// * - passID   -> uuid format
+// * - seriesID -> uuid format
+// * - section  -> string (must be equal to the section name in every event)
+// * - ownerID  -> uuid format
+// * - tokenID  -> hexadecimal string representing the tokenID of the public blockchain (uint256)
+// * - currency -> ISO 4217 code of the currency used to pay the pass
+// * - paymentProvider  -> name of the payment processor
+// * - paymentReference -> id of the payment in the payment processor
+// * - merkleProofs -> JSON object with the hex encoded proof of each event, by event ID, that the owner is in its current sale phase allowlist (can be empty)
+//
+// The return value can be:
+// * - the pass created serialized in JSON format
+// * - error in case some event can not sell the ticket
+func (c *Contract) IssuePass(ctx common.ITickenTxContext, passID, seriesID, section, ownerID, tokenID, currency, paymentProvider, paymentReference, merkleProofs string) (*Pass, error) {
+	passIDParsed, err := uuid.Parse(passID)
+	if err != nil {
+		return nil, ccErr("error parsing pass id: %v", err)
+	}
+
+	ownerIDParsed, err := uuid.Parse(ownerID)
+	if err != nil {
+		return nil, ccErr("error parsing owner id: %v", err)
+	}
+	ownerID = ownerIDParsed.String()
+
+	existentPass, err := getPass(ctx, passIDParsed.String())
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+	if existentPass != nil {
+		return nil, ccErr("pass with ID %s already exists", passID)
+	}
+
+	if len(currency) == 0 {
+		return nil, ccErr("currency is required")
+	}
+	if len(paymentReference) == 0 {
+		return nil, ccErr("payment reference is required")
+	}
+
+	// each event of the series has its own allowlists,
+	// so the owner needs a different proof for each one
+	merkleProofsParsed := make(map[string][]string)
+	if len(merkleProofs) > 0 {
+		if err := json.Unmarshal([]byte(merkleProofs), &merkleProofsParsed); err != nil {
+			return nil, ccErr("error parsing merkle proofs: %v", err)
+		}
+	}
+
+	passSecret, err := getTicketSecret(ctx)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	seriesJSON, err := ctx.GetInvoker(ccEventName).Invoke(ccEventGetSeriesFunc, seriesID)
+	if err != nil {
+		return nil, ccErr("%s", err)
+	}
+
+	var series passSeries
+	if err := json.Unmarshal(seriesJSON, &series); err != nil {
+		return nil, ccErr("failed to deserialize series: %v", err)
+	}
+
+	// the owner must be allowed in every event
+	// before any of their seats is sold
+	for _, eventID := range series.EventIDs {
+		if err := verifyAllowlist(ctx, eventID, section, ownerID, merkleProofsParsed[eventID]); err != nil {
+			return nil, err // this error is already formatted
+		}
+	}
+
+	saleJSON, err := ctx.GetInvoker(ccEventName).Invoke(ccEventSellPassFunc, seriesID, section, ownerID)
+	if err != nil {
+		return nil, ccErr("%s", err)
+	}
+
+	var sale passSale
+	if err := json.Unmarshal(saleJSON, &sale); err != nil {
+		return nil, ccErr("failed to deserialize pass sale: %v", err)
+	}
+
+	pass := Pass{
+		PassID:           passIDParsed.String(),
+		SeriesID:         seriesID,
+		Section:          section,
+		OwnerID:          ownerID,
+		Price:            sale.Price,
+		Currency:         strings.ToUpper(currency),
+		PaymentProvider:  paymentProvider,
+		PaymentReference: paymentReference,
+		TicketIDs:        make([]string, 0),
+	}
+
+	for _, eventSale := range sale.Sales {
+		// the ticket ID is derived from the pass
+		// and the event, so it is deterministic
+		ticketID := uuid.NewSHA1(passIDParsed, []byte(eventSale.EventID)).String()
+
+		ticket, err := c.newTicket(ctx, ticketID, eventSale.EventID, section, ownerID, tokenID)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+
+		ticket.Type = TicketTypePass
+		ticket.PassID = pass.PassID
+		ticket.Price = eventSale.Price
+		ticket.SalePhase = eventSale.SalePhase
+		ticket.Currency = pass.Currency
+		ticket.PaymentProvider = paymentProvider
+		ticket.PaymentReference = paymentReference
+
+		if err := putTicket(ctx, ticket); err != nil {
+			return nil, err // this error is already formatted
+		}
+
+		pass.TokenID = ticket.TokenID
+		pass.PurchasedAt = ticket.PurchasedAt
+		pass.TicketIDs = append(pass.TicketIDs, ticket.TicketID)
+	}
+
+	if err := putPass(ctx, &pass); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := putTicketSecret(ctx, pass.PassID, passSecret); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := common.EmitWaitlistOffers(ctx, sale.WaitlistOffers); err != nil {
+		return nil, ccErr("failed to emit waitlist offers: %v", err)
+	}
+
+	return &pass, nil
+}
+
+// GetPass returns the pass with ID "passID"
+//
+// Params
+// * - passID -> uuid format
+//
+// The return value can be:
+// * - the pass serialized in JSON format
+// * - error in case of the pass is not found
+func (c *Contract) GetPass(ctx common.ITickenTxContext, passID string) (*Pass, error) {
+	pass, err := getPass(ctx, passID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+	if pass == nil {
+		return nil, ccErr("pass %s does not exist", passID)
+	}
+
+	return pass, nil
+}
+
+// GetPassTickets returns the tickets of the pass with ID
+// "passID", one for each event of the series
+//
+// Params
+// * - passID -> uuid format
+//
+// The return value can be:
+// * - the list of tickets of the pass
+// * - error in case of the pass is not found
+func (c *Contract) GetPassTickets(ctx common.ITickenTxContext, passID string) ([]*Ticket, error) {
+	pass, err := c.GetPass(ctx, passID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	tickets := make([]*Ticket, 0)
+	for _, ticketID := range pass.TicketIDs {
+		ticket, err := c.GetTicket(ctx, ticketID)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	return tickets, nil
+}
+
+// VoidPass voids all the tickets of the pass with ID "passID" that
+// were not voided yet, as Void does. If "releaseSeat" is true, none
+// of the tickets of the pass can have been used. The caller must be
+// the organizer of the events or a platform admin
+//
+// Params
+// * - passID      -> uuid format
+// * - reason      -> reason of the void (chargeback, fraud, ban, etc)
+// * - releaseSeat -> "true" to return the seats to the section inventories
+//
+// The return value can be:
+// * - the tickets of the pass serialized in JSON format
+// * - error in case the pass can not be voided
+func (c *Contract) VoidPass(ctx common.ITickenTxContext, passID, reason, releaseSeat string) ([]*Ticket, error) {
+	tickets, err := c.GetPassTickets(ctx, passID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	releaseSeatParsed, err := strconv.ParseBool(releaseSeat)
+	if err != nil {
+		return nil, ccErr("error parsing release seat: %v", err)
+	}
+
+	if len(reason) == 0 {
+		return nil, ccErr("void reason is required")
+	}
+
+	waitlistOffers := make([]json.RawMessage, 0)
+	for _, ticket := range tickets {
+		if ticket.Status == TicketStatusVoided {
+			continue
+		}
+
+		ticketWaitlistOffers, err := voidTicket(ctx, ticket, reason, releaseSeatParsed)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+		waitlistOffers = append(waitlistOffers, ticketWaitlistOffers...)
+	}
+
+	if err := common.EmitWaitlistOffers(ctx, waitlistOffers); err != nil {
+		return nil, ccErr("failed to emit waitlist offers: %v", err)
+	}
+
+	return tickets, nil
+}
+
+// getPassTicketFor returns the ticket of the pass for the event in which
+// the validator "mspID"/"username" can scan at "gate". If many events
+// match, the ones whose ticket was not scanned and which are running are
+// preferred. If the validator can scan in no event, the first ticket is
+// returned, so the validator check of the caller fails on it. The
+// tickets already loaded in the transaction are taken from "tickets"
+func (c *Contract) getPassTicketFor(ctx common.ITickenTxContext, pass *Pass, mspID, username, gate string, tickets map[string]*Ticket) (*Ticket, error) {
+	var bestTicket *Ticket
+	bestScore := -1
+
+	for _, ticketID := range pass.TicketIDs {
+		ticket, ok := tickets[ticketID]
+		if !ok {
+			var err error
+			ticket, err = c.GetTicket(ctx, ticketID)
+			if err != nil {
+				return nil, err // this error is already formatted
+			}
+			tickets[ticketID] = ticket
+		}
+
+		isValidator, err := isEventValidator(ctx, ticket.EventID, mspID, username, gate)
+		if err != nil {
+			return nil, err // this error is already formatted
+		}
+
+		score := 0
+		if isValidator {
+			score += 4
+		}
+		if isValidator && ticket.Status != TicketStatusScanned {
+			score += 2
+
+			ev, err := getEvent(ctx, ticket.EventID)
+			if err != nil {
+				return nil, err // this error is already formatted
+			}
+			if ev.forSession(ticket.SessionID).Status == eventStatusRunning {
+				score += 1
+			}
+		}
+
+		if score > bestScore {
+			bestTicket = ticket
+			bestScore = score
+		}
+	}
+
+	return bestTicket, nil
+}
+
+func getPass(ctx common.ITickenTxContext, passID string) (*Pass, error) {
+	passKey, err := ctx.GetStub().CreateCompositeKey(passIndex, []string{passID})
+	if err != nil {
+		return nil, ccErr("failed to create pass key: %v", err)
+	}
+
+	passJSON, err := ctx.GetStub().GetState(passKey)
+	if err != nil {
+		return nil, ccErr("failed to read pass: %v", err)
+	}
+	if passJSON == nil {
+		return nil, nil
+	}
+
+	var pass Pass
+	if err := json.Unmarshal(passJSON, &pass); err != nil {
+		return nil, ccErr("failed to deserialize pass: %v", err)
+	}
+
+	return &pass, nil
+}
+
+func putPass(ctx common.ITickenTxContext, pass *Pass) error {
+	passKey, err := ctx.GetStub().CreateCompositeKey(passIndex, []string{pass.PassID})
+	if err != nil {
+		return ccErr("failed to create pass key: %v", err)
+	}
+
+	passJSON, err := json.Marshal(pass)
+	if err != nil {
+		return ccErr("failed to serialize pass: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(passKey, passJSON); err != nil {
+		return ccErr("failed to updated the state: %v", err)
+	}
+
+	return nil
+}
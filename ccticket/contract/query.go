@@ -0,0 +1,66 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+)
+
+// TicketPage is a page of the tickets found by QueryTickets
+type TicketPage struct {
+	Tickets []*Ticket `json:"tickets"`
+
+	// bookmark used to request the next page,
+	// empty when there are no more tickets
+	Bookmark string `json:"bookmark"`
+}
+
+// QueryTickets returns the tickets that match all the filters received,
+// ignoring the empty ones. The query runs on the CouchDB state database
+// using the indexes packaged in META-INF, and it fails with a clear
+// error if the peer runs LevelDB
+//
+// Params
+// * - ownerID  -> owner id in the web service database
+// * - status   -> status of the ticket
+// * - pageSize -> amount of tickets returned
+// * - bookmark -> bookmark returned by the previous page (empty for the first one)
+//
+// The return value can be:
+// * - the page of tickets serialized in JSON format (empty bookmark on the last page)
+// * - error in case some filter is invalid or the query can not run
+func (c *Contract) QueryTickets(ctx common.ITickenTxContext, ownerID, status, pageSize, bookmark string) (*TicketPage, error) {
+	selector := make(map[string]any)
+
+	if len(ownerID) > 0 {
+		selector["data.owner"] = ownerID
+	}
+
+	if len(status) > 0 {
+		selector["data.status"] = status
+	}
+
+	pageSizeParsed, err := strconv.Atoi(pageSize)
+	if err != nil {
+		return nil, ccErr("error converting page size: %v", err)
+	}
+
+	page, err := ticketSchema.Query(ctx, selector, pageSizeParsed, bookmark)
+	if err != nil {
+		return nil, ccErr("failed to query tickets: %v", err)
+	}
+
+	ticketPage := TicketPage{
+		Tickets:  make([]*Ticket, 0),
+		Bookmark: page.Bookmark,
+	}
+
+	for _, record := range page.Records {
+		var ticket Ticket
+		if _, err := ticketSchema.Unmarshal(record, &ticket); err != nil {
+			return nil, ccErr("failed to deserialize ticket: %v", err)
+		}
+		ticketPage.Tickets = append(ticketPage.Tickets, &ticket)
+	}
+
+	return &ticketPage, nil
+}
@@ -0,0 +1,336 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"math"
+	"time"
+)
+
+const refundIndex = "refund~eventID~ticketID"
+
+type RefundStatus string
+
+const (
+	// RefundStatusPending is the status of a refund
+	// waiting for the organizer to resolve it
+	RefundStatusPending RefundStatus = "pending"
+
+	// RefundStatusApproved is the status of a refund accepted
+	// by the organizer. The ticket is voided at this moment
+	RefundStatusApproved RefundStatus = "approved"
+
+	// RefundStatusRejected is the status of a refund
+	// rejected by the organizer
+	RefundStatusRejected RefundStatus = "rejected"
+)
+
+type Refund struct {
+	TicketID string `json:"ticket_id"`
+	EventID  string `json:"event_id"`
+	Section  string `json:"section"`
+	OwnerID  string `json:"owner"`
+
+	// amount to return to the owner, computed
+	// from the ticket price and the event policy
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+
+	Reason      string       `json:"reason"`
+	Status      RefundStatus `json:"status"`
+	RequestedAt time.Time    `json:"requested_at"`
+
+	// resolution information, only present
+	// once the refund is approved or rejected
+	ResolvedAt     time.Time `json:"resolved_at"`
+	ResolvedBy     string    `json:"resolved_by"`
+	ResolutionNote string    `json:"resolution_note"`
+}
+
+// RequestRefund files a refund request for the ticket with ID "ticketID"
+// on behalf of its owner. The request is only accepted if the event
+// refund policy allows refunds and its deadline, relative to the event
+// date, has not passed. The ticket stays valid until the organizer
+// approves the refund. If the ticket had a rejected request, a new
+// one replaces it. The owners are not identities of the network, so
+// the caller must be the ticket service acting on their behalf
+//
+// Params
+// * - ticketID -> uuid format
+// * - ownerID  -> uuid format (must be the owner of the ticket)
+// * - reason   -> reason of the request
+//
+// The return value can be:
+// * - the refund requested serialized in JSON format
+// * - error in case the refund can not be requested
+func (c *Contract) RequestRefund(ctx common.ITickenTxContext, ticketID, ownerID, reason string) (*Refund, error) {
+	isTicketService, err := ctx.HasRole(common.RoleTicketService)
+	if err != nil {
+		return nil, ccErr("could not get context identity: %v", err)
+	}
+	if !isTicketService {
+		return nil, ccErr("only the ticket service can request refunds")
+	}
+
+	ticket, err := c.GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if ticket.OwnerID != ownerID {
+		return nil, ccErr("ticket %s does not belong to owner %s", ticketID, ownerID)
+	}
+
+	if ticket.Status == TicketStatusScanned || ticket.Status == TicketStatusVoided {
+		return nil, ccErr("ticket %s on status %s can not be refunded", ticketID, ticket.Status)
+	}
+
+	if ticket.Type == TicketTypeComp {
+		return nil, ccErr("comp ticket %s can not be refunded", ticketID)
+	}
+
+	if ticket.Type == TicketTypePass {
+		return nil, ccErr("ticket %s is part of pass %s and can not be refunded alone", ticketID, ticket.PassID)
+	}
+
+	existentRefund, err := getRefund(ctx, ticket.EventID, ticket.TicketID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+	if existentRefund != nil && existentRefund.Status != RefundStatusRejected {
+		return nil, ccErr("ticket %s already has a refund on status %s", ticketID, existentRefund.Status)
+	}
+
+	ev, err := getEvent(ctx, ticket.EventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+	ev = ev.forSession(ticket.SessionID)
+
+	if ev.RefundPolicy == nil || ev.RefundPolicy.Percentage == 0 {
+		return nil, ccErr("event %s does not allow refunds", ticket.EventID)
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	deadline := ev.Date.Add(-time.Duration(ev.RefundPolicy.DeadlineHours) * time.Hour)
+	if !now.Before(deadline) {
+		return nil, ccErr("the refund deadline of event %s was %s", ticket.EventID, deadline.Format(time.RFC3339))
+	}
+
+	refund := Refund{
+		TicketID:    ticket.TicketID,
+		EventID:     ticket.EventID,
+		Section:     ticket.Section,
+		OwnerID:     ticket.OwnerID,
+		Amount:      math.Round(ticket.Price*ev.RefundPolicy.Percentage) / 100,
+		Currency:    ticket.Currency,
+		Reason:      reason,
+		Status:      RefundStatusPending,
+		RequestedAt: now,
+	}
+
+	if err := putRefund(ctx, &refund); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &refund, nil
+}
+
+// ApproveRefund accepts the pending refund of the ticket with ID
+// "ticketID". The ticket is voided and, while the event is still
+// on sale, its seat is returned to the inventory. Tickets that were
+// already scanned can not be refunded. The caller must be the event
+// organizer or a platform admin
+//
+// Params
+// * - ticketID -> uuid format
+// * - note     -> resolution note
+//
+// The return value can be:
+// * - the refund approved serialized in JSON format
+// * - error in case the refund can not be approved
+func (c *Contract) ApproveRefund(ctx common.ITickenTxContext, ticketID, note string) (*Refund, error) {
+	ticket, err := c.GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	refund, err := getPendingRefund(ctx, ticket)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	// the ticket could be scanned after
+	// the refund was requested
+	if ticket.Status == TicketStatusScanned {
+		return nil, ccErr("ticket %s was already scanned and can not be refunded", ticketID)
+	}
+
+	ev, err := getEvent(ctx, ticket.EventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	// voidTicket checks that the
+	// caller is allowed to do it
+	releaseSeat := ev.forSession(ticket.SessionID).Status == eventStatusOnSale
+	waitlistOffers, err := voidTicket(ctx, ticket, "refund: "+refund.Reason, releaseSeat)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := common.EmitWaitlistOffers(ctx, waitlistOffers); err != nil {
+		return nil, ccErr("failed to emit waitlist offers: %v", err)
+	}
+
+	if err := resolveRefund(ctx, refund, RefundStatusApproved, note); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return refund, nil
+}
+
+// RejectRefund rejects the pending refund of the ticket with ID
+// "ticketID". The ticket remains valid. The caller must be the
+// event organizer or a platform admin
+//
+// Params
+// * - ticketID -> uuid format
+// * - note     -> resolution note
+//
+// The return value can be:
+// * - the refund rejected serialized in JSON format
+// * - error in case the refund can not be rejected
+func (c *Contract) RejectRefund(ctx common.ITickenTxContext, ticketID, note string) (*Refund, error) {
+	ticket, err := c.GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	refund, err := getPendingRefund(ctx, ticket)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkEventOrganizerOrAdmin(ctx, ticket.EventID); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := resolveRefund(ctx, refund, RefundStatusRejected, note); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return refund, nil
+}
+
+// GetPendingRefunds returns the refunds of the event with ID
+// "eventID" waiting for the organizer to resolve them
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the list of pending refunds (possibly empty)
+// * - error in case the refunds could not be read
+func (c *Contract) GetPendingRefunds(ctx common.ITickenTxContext, eventID string) ([]*Refund, error) {
+	refundsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(refundIndex, []string{eventID})
+	if err != nil {
+		return nil, ccErr("failed to create a refunds iterator: %v", err)
+	}
+	defer refundsIterator.Close()
+
+	refunds := make([]*Refund, 0)
+	for refundsIterator.HasNext() {
+		queryResult, err := refundsIterator.Next()
+		if err != nil {
+			return nil, ccErr("failed to read refund: %v", err)
+		}
+
+		var refund Refund
+		if err := json.Unmarshal(queryResult.Value, &refund); err != nil {
+			return nil, ccErr("failed to deserialize refund: %v", err)
+		}
+
+		if refund.Status == RefundStatusPending {
+			refunds = append(refunds, &refund)
+		}
+	}
+
+	return refunds, nil
+}
+
+func resolveRefund(ctx common.ITickenTxContext, refund *Refund, status RefundStatus, note string) error {
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return ccErr("could not get context identity: %v", err)
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return ccErr("failed to get transaction time: %v", err)
+	}
+
+	refund.Status = status
+	refund.ResolvedAt = now
+	refund.ResolvedBy = username + "@" + mspID
+	refund.ResolutionNote = note
+
+	return putRefund(ctx, refund)
+}
+
+func getPendingRefund(ctx common.ITickenTxContext, ticket *Ticket) (*Refund, error) {
+	refund, err := getRefund(ctx, ticket.EventID, ticket.TicketID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if refund == nil || refund.Status != RefundStatusPending {
+		return nil, ccErr("ticket %s has no pending refund", ticket.TicketID)
+	}
+
+	return refund, nil
+}
+
+func getRefund(ctx common.ITickenTxContext, eventID, ticketID string) (*Refund, error) {
+	refundKey, err := ctx.GetStub().CreateCompositeKey(refundIndex, []string{eventID, ticketID})
+	if err != nil {
+		return nil, ccErr("failed to create refund key: %v", err)
+	}
+
+	refundJSON, err := ctx.GetStub().GetState(refundKey)
+	if err != nil {
+		return nil, ccErr("failed to read refund: %v", err)
+	}
+	if refundJSON == nil {
+		return nil, nil
+	}
+
+	var refund Refund
+	if err := json.Unmarshal(refundJSON, &refund); err != nil {
+		return nil, ccErr("failed to deserialize refund: %v", err)
+	}
+
+	return &refund, nil
+}
+
+func putRefund(ctx common.ITickenTxContext, refund *Refund) error {
+	refundKey, err := ctx.GetStub().CreateCompositeKey(refundIndex, []string{refund.EventID, refund.TicketID})
+	if err != nil {
+		return ccErr("failed to create refund key: %v", err)
+	}
+
+	refundJSON, err := json.Marshal(refund)
+	if err != nil {
+		return ccErr("failed to serialize refund: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(refundKey, refundJSON); err != nil {
+		return ccErr("failed to updated the state: %v", err)
+	}
+
+	return nil
+}
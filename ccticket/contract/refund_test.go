@@ -0,0 +1,68 @@
+package contract
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestRefundAuthorization(t *testing.T) {
+	const eventID = "22222222-2222-2222-2222-222222222222"
+	const ticketID = "33333333-3333-3333-3333-333333333331"
+	const ownerID = "44444444-4444-4444-4444-444444444441"
+
+	tests := []struct {
+		name     string
+		identity testIdentity
+		wantErr  string
+	}{
+		{name: "ticket service", identity: ticketServiceIdentity},
+		{name: "organizer", identity: organizerIdentity, wantErr: "only the ticket service"},
+		{name: "platform admin", identity: adminIdentity, wantErr: "only the ticket service"},
+		{name: "identity without role", identity: testIdentity{mspID: "Org2MSP", username: "owner"}, wantErr: "only the ticket service"},
+	}
+
+	// the transactions of the mock stub
+	// are timestamped with the current time
+	ev := &event{
+		EventID:           eventID,
+		Status:            eventStatusOnSale,
+		Date:              time.Now().Add(72 * time.Hour),
+		MSPID:             organizerIdentity.mspID,
+		OrganizerUsername: organizerIdentity.username,
+		RefundPolicy:      &refundPolicy{DeadlineHours: 24, Percentage: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newTestStub(t, ev, &Ticket{
+				TicketID: ticketID,
+				EventID:  eventID,
+				Section:  "vip",
+				OwnerID:  ownerID,
+				Type:     TicketTypeStandard,
+				Status:   TicketStatusIssued,
+				Price:    10,
+				Currency: "USD",
+			})
+
+			stub.MockTransactionStart("refund")
+			refund, err := new(Contract).RequestRefund(newTestContext(stub, tt.identity), ticketID, ownerID, "can not attend")
+			stub.MockTransactionEnd("refund")
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RequestRefund() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RequestRefund() error = %v", err)
+			}
+
+			if refund.Status != RefundStatusPending || refund.Amount != 5 {
+				t.Errorf("refund = %s of %v, want %s of 5", refund.Status, refund.Amount, RefundStatusPending)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"math"
+)
+
+// SectionRevenue is the amount collected by the tickets
+// issued for a section in a given currency
+type SectionRevenue struct {
+	EventID  string  `json:"event_id"`
+	Section  string  `json:"section"`
+	Currency string  `json:"currency"`
+	Tickets  int     `json:"tickets"`
+	Revenue  float64 `json:"revenue"`
+}
+
+// GetEventRevenue sums the price of the tickets issued for the
+// event with ID "eventID" that were not voided, grouped by section
+// and currency.
+// The result is computed from the tickets stored in the ledger,
+// so it can be used to reconcile the payment processors exports
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the revenue of each section (possibly empty)
+// * - error in case the tickets could not be read
+func (c *Contract) GetEventRevenue(ctx common.ITickenTxContext, eventID string) ([]*SectionRevenue, error) {
+	return getRevenue(ctx, eventID)
+}
+
+// GetSectionRevenue sums the price of the tickets issued for
+// the section "section" of the event with ID "eventID" that were
+// not voided, grouped by currency
+//
+// Params
+// * - eventID -> uuid format
+// * - section -> string (must be equal to the section name of the event)
+//
+// The return value can be:
+// * - the revenue of the section for each currency (possibly empty)
+// * - error in case the tickets could not be read
+func (c *Contract) GetSectionRevenue(ctx common.ITickenTxContext, eventID, section string) ([]*SectionRevenue, error) {
+	return getRevenue(ctx, eventID, section)
+}
+
+func getRevenue(ctx common.ITickenTxContext, eventID string, section ...string) ([]*SectionRevenue, error) {
+	ticketsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, append([]string{eventID}, section...))
+	if err != nil {
+		return nil, ccErr("failed to create a ticket iterator: %v", err)
+	}
+	defer ticketsIterator.Close()
+
+	tickets, err := constructQueryResponseFromIterator(ctx, ticketsIterator)
+	if err != nil {
+		return nil, ccErr("failed to read tickets: %v", err)
+	}
+
+	revenues := make([]*SectionRevenue, 0)
+	for _, ticket := range tickets {
+		// comp tickets are free, and the voided
+		// ones were refunded or never paid
+		if ticket.Type == TicketTypeComp || ticket.Status == TicketStatusVoided {
+			continue
+		}
+
+		revenue := findRevenue(revenues, ticket.Section, ticket.Currency)
+		if revenue == nil {
+			revenue = &SectionRevenue{
+				EventID:  eventID,
+				Section:  ticket.Section,
+				Currency: ticket.Currency,
+			}
+			revenues = append(revenues, revenue)
+		}
+
+		revenue.Tickets += 1
+		revenue.Revenue += ticket.Price
+	}
+
+	// avoid floating point noise
+	// from the accumulated sums
+	for _, revenue := range revenues {
+		revenue.Revenue = math.Round(revenue.Revenue*100) / 100
+	}
+
+	return revenues, nil
+}
+
+func findRevenue(revenues []*SectionRevenue, section, currency string) *SectionRevenue {
+	for _, revenue := range revenues {
+		if revenue.Section == section && revenue.Currency == currency {
+			return revenue
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+)
+
+// Scan admits the ticket with ID "ticketID" at the gate "gate". The
+// caller must be a validator of the event allowed to scan at the gate,
+// the event must be "running" and "code" must be the current rotating
+// code of the ticket. The codes are derived from the ticket secret and
+// the transaction timestamp, so a copied QR is useless after a short
+// time. Each ticket can only be scanned once. The ID of a pass can be
+// used as "ticketID": the ticket of the pass for the running event of
+// the validator is scanned, so the pass is admitted once per event
+//
+// Params
+// * - ticketID -> uuid format (ticket or pass ID)
+// * - gate     -> name of the gate where the ticket is scanned
+// * - code     -> time based code shown by the ticket owner
+//
+// The return value can be:
+// * - the ticket scanned serialized in JSON format
+// * - error in case the ticket can not be admitted
+func (c *Contract) Scan(ctx common.ITickenTxContext, ticketID, gate, code string) (*Ticket, error) {
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return nil, ccErr("could not get context identity: %v", err)
+	}
+
+	ticket, err := c.getTicketToScan(ctx, ticketID, mspID, username, gate, make(map[string]*Ticket))
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+	if ticket == nil {
+		return nil, ccErr("ticket %s does not exist", ticketID)
+	}
+
+	if ticket.Status == TicketStatusScanned {
+		return nil, ccErr("ticket %s was already scanned at %s", ticketID, ticket.ScannedAt)
+	}
+
+	if ticket.Status == TicketStatusVoided {
+		return nil, ccErr("ticket %s is voided: %s", ticketID, ticket.VoidReason)
+	}
+
+	if err := checkValidator(ctx, ticket.EventID, gate); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	ev, err := getEvent(ctx, ticket.EventID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+	if ev.forSession(ticket.SessionID).Status != eventStatusRunning {
+		return nil, ccErr("event %s is not running", ticket.EventID)
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	// the tickets of a pass share
+	// the secret of the pass
+	secretID := ticket.TicketID
+	if len(ticket.PassID) > 0 {
+		secretID = ticket.PassID
+	}
+
+	ticketSecret, err := ctx.GetStub().GetPrivateData(ticketSecretsCollection, secretID)
+	if err != nil {
+		return nil, ccErr("failed to read ticket secret: %v", err)
+	}
+	if ticketSecret == nil {
+		return nil, ccErr("ticket %s has no validation secret", ticketID)
+	}
+
+	if !common.VerifyTOTP(ticketSecret, code, now) {
+		return nil, ccErr("invalid or expired validation code for ticket %s", ticketID)
+	}
+
+	ticket.Status = TicketStatusScanned
+	ticket.ScannedAt = now
+	ticket.ScannedGate = gate
+	ticket.ScannedBy = username + "@" + mspID
+
+	if err := putTicket(ctx, ticket); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return ticket, nil
+}
+
+// getTicketToScan returns the ticket with ID "ticketID", or the ticket
+// of the pass with that ID that the validator "mspID"/"username" can
+// scan at "gate". Nil is returned if neither of them exists. The tickets
+// already loaded in the transaction are taken from "tickets"
+func (c *Contract) getTicketToScan(ctx common.ITickenTxContext, ticketID, mspID, username, gate string, tickets map[string]*Ticket) (*Ticket, error) {
+	pass, err := getPass(ctx, ticketID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+	if pass != nil {
+		return c.getPassTicketFor(ctx, pass, mspID, username, gate, tickets)
+	}
+
+	ticket, ok := tickets[ticketID]
+	if !ok {
+		ticket, _ = c.GetTicket(ctx, ticketID)
+		tickets[ticketID] = ticket
+	}
+
+	return ticket, nil
+}
+
+func getEvent(ctx common.ITickenTxContext, eventID string) (*event, error) {
+	eventJSON, err := ctx.GetInvoker(ccEventName).Invoke(ccEventGetEventFunc, eventID)
+	if err != nil {
+		return nil, ccErr("%s", err)
+	}
+
+	var ev event
+	if err := json.Unmarshal(eventJSON, &ev); err != nil {
+		return nil, ccErr("failed to deserialize event: %v", err)
+	}
+
+	return &ev, nil
+}
+
+// forSession returns the event as seen by the tickets of the session
+// "sessionID", with the date, status and sections of the session.
+// Events with sessions are not started or finished as a whole, so the
+// status of the session decides if its tickets can be scanned
+func (ev *event) forSession(sessionID string) *event {
+	for _, s := range ev.Sessions {
+		if s.SessionID != sessionID {
+			continue
+		}
+
+		sessionEvent := *ev
+		sessionEvent.Date = s.Date
+		sessionEvent.StartedAt = s.StartedAt
+		sessionEvent.Sections = s.Sections
+		sessionEvent.Status = s.Status
+		return &sessionEvent
+	}
+
+	return ev
+}
@@ -0,0 +1,88 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+)
+
+// SectionTicketStats counts the tickets of a section of the
+// event stored in cc-ticket. It is used by cc-event to build
+// the statistics of the event
+type SectionTicketStats struct {
+	Section string `json:"section"`
+
+	// session of the section, empty
+	// for events without sessions
+	Session string `json:"session"`
+
+	// tickets that were not voided
+	ValidTickets   int `json:"valid_tickets"`
+	ScannedTickets int `json:"scanned_tickets"`
+	VoidedTickets  int `json:"voided_tickets"`
+
+	// amount collected by the tickets that were
+	// not voided, grouped by currency
+	Revenue []*common.CurrencyRevenue `json:"revenue"`
+}
+
+// GetEventTicketStats counts the tickets of every section of the event
+// with ID "eventID" by status, and sums the price of the tickets that
+// were not voided. For events with sessions, the sections of every
+// session are counted apart
+//
+// Params
+// * - eventID -> uuid format
+//
+// The return value can be:
+// * - the stats of each section with tickets (possibly empty)
+// * - error in case the tickets could not be read
+func (c *Contract) GetEventTicketStats(ctx common.ITickenTxContext, eventID string) ([]*SectionTicketStats, error) {
+	ticketsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{eventID})
+	if err != nil {
+		return nil, ccErr("failed to create a ticket iterator: %v", err)
+	}
+	defer ticketsIterator.Close()
+
+	tickets, err := constructQueryResponseFromIterator(ctx, ticketsIterator)
+	if err != nil {
+		return nil, ccErr("failed to read tickets: %v", err)
+	}
+
+	stats := make([]*SectionTicketStats, 0)
+	statsBySection := make(map[sectionKey]*SectionTicketStats)
+	for _, ticket := range tickets {
+		key := sectionKey{ticket.SessionID, ticket.Section}
+
+		sectionStats, ok := statsBySection[key]
+		if !ok {
+			sectionStats = &SectionTicketStats{
+				Section: ticket.Section,
+				Session: ticket.SessionID,
+				Revenue: make([]*common.CurrencyRevenue, 0),
+			}
+			statsBySection[key] = sectionStats
+			stats = append(stats, sectionStats)
+		}
+
+		if ticket.Status == TicketStatusVoided {
+			sectionStats.VoidedTickets += 1
+			continue
+		}
+
+		sectionStats.ValidTickets += 1
+		if ticket.Status == TicketStatusScanned {
+			sectionStats.ScannedTickets += 1
+		}
+
+		// comp tickets are free
+		if ticket.Type == TicketTypeComp {
+			continue
+		}
+		sectionStats.Revenue = common.AddRevenue(sectionStats.Revenue, ticket.Currency, ticket.Price)
+	}
+
+	for _, sectionStats := range stats {
+		common.RoundRevenue(sectionStats.Revenue)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,89 @@
+package contract
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"testing"
+)
+
+func TestGetEventTicketStats(t *testing.T) {
+	const eventID = "22222222-2222-2222-2222-222222222222"
+	const sessionID = "88888888-8888-8888-8888-888888888881"
+
+	tickets := []*Ticket{
+		{TicketID: "33333333-3333-3333-3333-333333333331", Section: "vip", Type: TicketTypeStandard, Status: TicketStatusIssued, Price: 10.1, Currency: "USD"},
+		{TicketID: "33333333-3333-3333-3333-333333333332", Section: "vip", Type: TicketTypeStandard, Status: TicketStatusScanned, Price: 20.2, Currency: "USD"},
+		{TicketID: "33333333-3333-3333-3333-333333333333", Section: "vip", Type: TicketTypeStandard, Status: TicketStatusVoided, Price: 30, Currency: "USD"},
+		{TicketID: "33333333-3333-3333-3333-333333333334", Section: "vip", Type: TicketTypeComp, Status: TicketStatusIssued, Price: 40, Currency: "USD"},
+		{TicketID: "33333333-3333-3333-3333-333333333335", Section: "vip", Type: TicketTypeStandard, Status: TicketStatusIssued, Price: 5, Currency: "EUR"},
+		{TicketID: "33333333-3333-3333-3333-333333333336", Section: "vip", SessionID: sessionID, Type: TicketTypeStandard, Status: TicketStatusIssued, Price: 15, Currency: "USD"},
+	}
+
+	stub := shimtest.NewMockStub(Name, nil)
+	ctx := common.NewTransactionContext()
+	ctx.SetStub(stub)
+
+	stub.MockTransactionStart("setup")
+	for _, ticket := range tickets {
+		ticket.EventID = eventID
+		if err := putTicket(ctx, ticket); err != nil {
+			t.Fatalf("putTicket() error = %v", err)
+		}
+	}
+	stub.MockTransactionEnd("setup")
+
+	stub.MockTransactionStart("stats")
+	stats, err := new(Contract).GetEventTicketStats(ctx, eventID)
+	stub.MockTransactionEnd("stats")
+	if err != nil {
+		t.Fatalf("GetEventTicketStats() error = %v", err)
+	}
+
+	want := map[sectionKey]SectionTicketStats{
+		{"", "vip"}: {
+			ValidTickets:   4,
+			ScannedTickets: 1,
+			VoidedTickets:  1,
+			Revenue: []*common.CurrencyRevenue{
+				{Currency: "USD", Amount: 30.3},
+				{Currency: "EUR", Amount: 5},
+			},
+		},
+		{sessionID, "vip"}: {
+			ValidTickets: 1,
+			Revenue: []*common.CurrencyRevenue{
+				{Currency: "USD", Amount: 15},
+			},
+		},
+	}
+
+	if len(stats) != len(want) {
+		t.Fatalf("got the stats of %d sections, want %d", len(stats), len(want))
+	}
+	for _, sectionStats := range stats {
+		key := sectionKey{sectionStats.Session, sectionStats.Section}
+		wantStats, ok := want[key]
+		if !ok {
+			t.Errorf("unexpected stats of section %+v", key)
+			continue
+		}
+
+		if sectionStats.ValidTickets != wantStats.ValidTickets ||
+			sectionStats.ScannedTickets != wantStats.ScannedTickets ||
+			sectionStats.VoidedTickets != wantStats.VoidedTickets {
+			t.Errorf("section %+v counters = %d/%d/%d, want %d/%d/%d", key,
+				sectionStats.ValidTickets, sectionStats.ScannedTickets, sectionStats.VoidedTickets,
+				wantStats.ValidTickets, wantStats.ScannedTickets, wantStats.VoidedTickets)
+		}
+
+		if len(sectionStats.Revenue) != len(wantStats.Revenue) {
+			t.Errorf("section %+v has the revenue of %d currencies, want %d", key, len(sectionStats.Revenue), len(wantStats.Revenue))
+			continue
+		}
+		for i, revenue := range wantStats.Revenue {
+			if *sectionStats.Revenue[i] != *revenue {
+				t.Errorf("section %+v revenue %d = %+v, want %+v", key, i, *sectionStats.Revenue[i], *revenue)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package contract
+
+import (
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+)
+
+// checkValidator asks cc-event if the identity that submitted the
+// transaction is a validator of the event allowed to scan at "gate"
+func checkValidator(ctx common.ITickenTxContext, eventID, gate string) error {
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return ccErr("could not get context identity: %v", err)
+	}
+
+	isValidator, err := isEventValidator(ctx, eventID, mspID, username, gate)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	if !isValidator {
+		return ccErr("%s@%s is not allowed to scan tickets of event %s at gate %s", username, mspID, eventID, gate)
+	}
+
+	return nil
+}
+
+func isEventValidator(ctx common.ITickenTxContext, eventID, mspID, username, gate string) (bool, error) {
+	isValidatorResponse, err := ctx.GetInvoker(ccEventName).Invoke(ccEventIsValidatorFunc, eventID, mspID, username, gate)
+	if err != nil {
+		return false, ccErr("%s", err)
+	}
+
+	isValidator, err := strconv.ParseBool(string(isValidatorResponse))
+	if err != nil {
+		return false, ccErr("failed to parse validator response: %v", err)
+	}
+
+	return isValidator, nil
+}
@@ -0,0 +1,164 @@
+package contract
+
+import (
+	"encoding/json"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+)
+
+// Void invalidates the ticket with ID "ticketID", so it can no longer
+// be scanned. The reason is stored in the ticket, so it is part of the
+// ticket history. Optionally, the seat is returned to the inventory by
+// calling cc-event, which is only possible if the ticket was not used.
+// The caller must be the event organizer or a platform admin
+//
+// Params
+// * - ticketID    -> uuid format
+// * - reason      -> reason of the void (chargeback, fraud, ban, etc)
+// * - releaseSeat -> "true" to return the seat to the section inventory
+//
+// The return value can be:
+// * - the ticket voided serialized in JSON format
+// * - error in case the ticket can not be voided
+func (c *Contract) Void(ctx common.ITickenTxContext, ticketID, reason, releaseSeat string) (*Ticket, error) {
+	ticket, err := c.GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	releaseSeatParsed, err := strconv.ParseBool(releaseSeat)
+	if err != nil {
+		return nil, ccErr("error parsing release seat: %v", err)
+	}
+
+	if len(reason) == 0 {
+		return nil, ccErr("void reason is required")
+	}
+
+	waitlistOffers, err := voidTicket(ctx, ticket, reason, releaseSeatParsed)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := common.EmitWaitlistOffers(ctx, waitlistOffers); err != nil {
+		return nil, ccErr("failed to emit waitlist offers: %v", err)
+	}
+
+	return ticket, nil
+}
+
+// GetTicketHistory returns all the versions of the ticket with
+// ID "ticketID" stored in the ledger, from the newest to the oldest
+//
+// Params
+// * - ticketID -> uuid format
+//
+// The return value can be:
+// * - the list of versions of the ticket
+// * - error in case the history could not be read
+func (c *Contract) GetTicketHistory(ctx common.ITickenTxContext, ticketID string) ([]*common.HistoryQueryResult, error) {
+	historyIterator, err := ctx.GetStub().GetHistoryForKey(ticketID)
+	if err != nil {
+		return nil, ccErr("failed to create a ticket history iterator: %v", err)
+	}
+	defer historyIterator.Close()
+
+	history := make([]*common.HistoryQueryResult, 0)
+	for historyIterator.HasNext() {
+		modification, err := historyIterator.Next()
+		if err != nil {
+			return nil, ccErr("failed to read ticket history: %v", err)
+		}
+
+		history = append(history, &common.HistoryQueryResult{
+			Record:    modification.Value,
+			TxId:      modification.TxId,
+			Timestamp: modification.Timestamp.AsTime(),
+			IsDelete:  modification.IsDelete,
+		})
+	}
+
+	return history, nil
+}
+
+// voidTicket marks the ticket as voided after checking that the caller
+// is the event organizer or a platform admin, and releases its seat
+// in cc-event if "releaseSeat" is true. The offers made by cc-event to
+// waitlisted owners are returned, so the caller can emit them
+func voidTicket(ctx common.ITickenTxContext, ticket *Ticket, reason string, releaseSeat bool) ([]json.RawMessage, error) {
+	if ticket.Status == TicketStatusVoided {
+		return nil, ccErr("ticket %s is already voided", ticket.TicketID)
+	}
+
+	if err := checkEventOrganizerOrAdmin(ctx, ticket.EventID); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return nil, ccErr("could not get context identity: %v", err)
+	}
+
+	waitlistOffers := make([]json.RawMessage, 0)
+	if releaseSeat {
+		if ticket.Status == TicketStatusScanned {
+			return nil, ccErr("ticket %s was already used and its seat can not be released", ticket.TicketID)
+		}
+
+		waitlistOffersJSON, err := ctx.GetInvoker(ccEventName).Invoke(ccEventReleaseTicketFunc, ticket.EventID, ticket.SessionID, ticket.Section, ticket.Allotment)
+		if err != nil {
+			return nil, ccErr("%s", err)
+		}
+
+		// the seat can be offered
+		// to a waitlisted owner
+		if err := json.Unmarshal(waitlistOffersJSON, &waitlistOffers); err != nil {
+			return nil, ccErr("failed to deserialize waitlist offers: %v", err)
+		}
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return nil, ccErr("failed to get transaction time: %v", err)
+	}
+
+	ticket.Status = TicketStatusVoided
+	ticket.VoidedAt = now
+	ticket.VoidedBy = username + "@" + mspID
+	ticket.VoidReason = reason
+	ticket.SeatReleased = releaseSeat
+
+	if err := putTicket(ctx, ticket); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return waitlistOffers, nil
+}
+
+// checkEventOrganizerOrAdmin fails if the identity that submitted the
+// transaction is neither the organizer of the event nor a platform admin
+func checkEventOrganizerOrAdmin(ctx common.ITickenTxContext, eventID string) error {
+	isAdmin, err := isPlatformAdmin(ctx)
+	if err != nil {
+		return err // this error is already formatted
+	}
+	if isAdmin {
+		return nil
+	}
+
+	ev, err := getEvent(ctx, eventID)
+	if err != nil {
+		return err // this error is already formatted
+	}
+
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return ccErr("could not get context identity: %v", err)
+	}
+
+	if ev.MSPID != mspID || ev.OrganizerUsername != username {
+		return ccErr("only the organizer of the event %s or an admin can perform this operation", eventID)
+	}
+
+	return nil
+}
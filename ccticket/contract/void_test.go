@@ -0,0 +1,60 @@
+package contract
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVoidAuthorization(t *testing.T) {
+	const eventID = "22222222-2222-2222-2222-222222222222"
+	const ticketID = "33333333-3333-3333-3333-333333333331"
+
+	tests := []struct {
+		name     string
+		identity testIdentity
+		wantErr  string
+	}{
+		{name: "organizer", identity: organizerIdentity},
+		{name: "platform admin", identity: adminIdentity},
+		{name: "other identity", identity: testIdentity{mspID: "Org2MSP", username: "org1"}, wantErr: "only the organizer"},
+		{name: "ticket service", identity: ticketServiceIdentity, wantErr: "only the organizer"},
+	}
+
+	ev := &event{
+		EventID:           eventID,
+		Status:            eventStatusOnSale,
+		MSPID:             organizerIdentity.mspID,
+		OrganizerUsername: organizerIdentity.username,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newTestStub(t, ev, &Ticket{
+				TicketID: ticketID,
+				EventID:  eventID,
+				Section:  "vip",
+				Type:     TicketTypeStandard,
+				Status:   TicketStatusIssued,
+			})
+
+			stub.MockTransactionStart("void")
+			ticket, err := new(Contract).Void(newTestContext(stub, tt.identity), ticketID, "fraud", "false")
+			stub.MockTransactionEnd("void")
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Void() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Void() error = %v", err)
+			}
+
+			if ticket.Status != TicketStatusVoided || ticket.VoidedBy != tt.identity.username+"@"+tt.identity.mspID {
+				t.Errorf("ticket status = %s voided by %q, want %s voided by %s@%s",
+					ticket.Status, ticket.VoidedBy, TicketStatusVoided, tt.identity.username, tt.identity.mspID)
+			}
+		})
+	}
+}
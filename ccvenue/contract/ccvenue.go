@@ -0,0 +1,222 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"strconv"
+)
+
+type Contract struct {
+	contractapi.Contract
+}
+
+const Name = "cc-venue"
+
+type Venue struct {
+	VenueID string `json:"venue_id"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+
+	// reference to the venue layout (ex: the
+	// url of the seat map) used by the clients
+	// to draw the venue
+	Layout string `json:"layout"`
+
+	// max amount of people that the venue
+	// is legally allowed to hold
+	Capacity int     `json:"capacity"`
+	Areas    []*Area `json:"areas"`
+
+	// identity of the venue registrant
+	MSPID    string `json:"msp_id"`
+	Username string `json:"username"`
+}
+
+type Area struct {
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"`
+}
+
+// Register a new venue without any areas in the blockchain and returns
+// its value. The areas can be added on following transactions. Only
+// platform admins can register venues, because their capacity bounds
+// the tickets that the events can sell
+//
+// Params
+// * - venueID  -> uuid format
+// * - name     -> string
+// * - address  -> string
+// * - capacity -> legal capacity of the venue
+// * - layout   -> reference to the venue layout
+//
+// The return value can be:
+// * - the venue registered serialized in JSON format
+// * - error in case some conditions to register the venue are not fulfilled
+func (c *Contract) Register(ctx common.ITickenTxContext, venueID, name, address, capacity, layout string) (*Venue, error) {
+	if err := checkPlatformAdmin(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	existentVenue, _ := c.GetVenue(ctx, venueID)
+	if existentVenue != nil {
+		return nil, ccErr("venue with ID %s already exists", venueID)
+	}
+
+	venueIDParsed, err := uuid.Parse(venueID)
+	if err != nil {
+		return nil, ccErr("error parsing venue id: %v", err)
+	}
+
+	capacityParsed, err := strconv.Atoi(capacity)
+	if err != nil {
+		return nil, ccErr("error converting capacity: %v", err)
+	}
+	if capacityParsed <= 0 {
+		return nil, ccErr("invalid capacity value %d - capacity must be greater than 0", capacityParsed)
+	}
+
+	mspID, username, err := ctx.GetContextIdentity()
+	if err != nil {
+		return nil, ccErr("could not get context identity: %v", err)
+	}
+
+	venue := Venue{
+		VenueID:  venueIDParsed.String(),
+		Name:     name,
+		Address:  address,
+		Layout:   layout,
+		Capacity: capacityParsed,
+		Areas:    make([]*Area, 0),
+
+		MSPID:    mspID,
+		Username: username,
+	}
+
+	if err := c.putVenue(ctx, &venue); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &venue, nil
+}
+
+// AddArea adds a named area to the venue. Each area is identified
+// uniquely inside the venue for its name. The sum of the capacity
+// of all the areas can not exceed the capacity of the venue. Only
+// platform admins can add areas
+//
+// Params
+// * - venueID  -> uuid format
+// * - name     -> area name (must be unique)
+// * - capacity -> legal capacity of the area
+//
+// The return value can be:
+// * - the area added serialized in JSON format
+// * - error in case some conditions to add the area are not fulfilled
+func (c *Contract) AddArea(ctx common.ITickenTxContext, venueID, name, capacity string) (*Area, error) {
+	venue, err := c.GetVenue(ctx, venueID)
+	if err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	if err := checkPlatformAdmin(ctx); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	capacityParsed, err := strconv.Atoi(capacity)
+	if err != nil {
+		return nil, ccErr("error converting capacity: %v", err)
+	}
+	if capacityParsed <= 0 {
+		return nil, ccErr("invalid capacity value %d - capacity must be greater than 0", capacityParsed)
+	}
+
+	if venue.GetArea(name) != nil {
+		return nil, ccErr("area with name %s already exists", name)
+	}
+
+	areasCapacity := capacityParsed
+	for _, area := range venue.Areas {
+		areasCapacity += area.Capacity
+	}
+	if areasCapacity > venue.Capacity {
+		return nil, ccErr("areas capacity %d exceeds venue capacity %d", areasCapacity, venue.Capacity)
+	}
+
+	newArea := Area{
+		Name:     name,
+		Capacity: capacityParsed,
+	}
+
+	venue.Areas = append(venue.Areas, &newArea)
+
+	if err := c.putVenue(ctx, venue); err != nil {
+		return nil, err // this error is already formatted
+	}
+
+	return &newArea, nil
+}
+
+// GetVenue returns the venue information of the venue with id "venueID".
+//
+// Params
+// * - venueID -> uuid format
+//
+// The return value can be:
+// * - error in case of the venue is not found
+func (c *Contract) GetVenue(ctx common.ITickenTxContext, venueID string) (*Venue, error) {
+	venueJSON, err := ctx.GetStub().GetState(venueID)
+	if err != nil {
+		return nil, ccErr("failed to read venue: %v", err)
+	}
+	if venueJSON == nil {
+		return nil, ccErr("the venue %s does not exist", venueID)
+	}
+
+	var venue Venue
+	if err := json.Unmarshal(venueJSON, &venue); err != nil {
+		return nil, ccErr("failed to deserialize venue: %v", err)
+	}
+
+	return &venue, nil
+}
+
+func (venue *Venue) GetArea(name string) *Area {
+	for _, area := range venue.Areas {
+		if area.Name == name {
+			return area
+		}
+	}
+	return nil
+}
+
+func (c *Contract) putVenue(ctx common.ITickenTxContext, venue *Venue) error {
+	venueJSON, err := json.Marshal(venue)
+	if err != nil {
+		return ccErr("failed to serialize venue: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(venue.VenueID, venueJSON); err != nil {
+		return ccErr("failed to update ledger: %v", err)
+	}
+
+	return nil
+}
+
+func checkPlatformAdmin(ctx common.ITickenTxContext) error {
+	isAdmin, err := ctx.IsPlatformAdmin()
+	if err != nil {
+		return ccErr("could not get context identity: %v", err)
+	}
+	if !isAdmin {
+		return ccErr("only platform admins can perform this operation")
+	}
+	return nil
+}
+
+func ccErr(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return fmt.Errorf("[%s] | %s", Name, msg)
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"ccvenue/contract"
+	"github.com/ticken-ts/ticken-chaincodes/common"
+	"github.com/ticken-ts/ticken-chaincodes/common/server"
+	"log"
+)
+
+func main() {
+	// add metadata and init transaction context
+	ccVenue := new(contract.Contract)
+	ccVenue.Name = contract.Name
+	ccVenue.Info.Title = "CC Venue"
+	ccVenue.TransactionContextHandler = common.NewTransactionContext()
+
+	if err := server.Run(ccVenue); err != nil {
+		log.Fatalf("error running %s chaincode: %s", contract.Name, err)
+	}
+}
@@ -0,0 +1,22 @@
+package common
+
+import "time"
+
+// HasPassed returns true if the moment "t" is before
+// or equal to the transaction timestamp
+func HasPassed(ctx ITickenTxContext, t time.Time) (bool, error) {
+	now, err := ctx.Now()
+	if err != nil {
+		return false, err
+	}
+	return !now.Before(t), nil
+}
+
+// WithinWindow returns true if "now" is inside the window
+// [from, to). A zero "to" is treated as an open end
+func WithinWindow(now, from, to time.Time) bool {
+	if now.Before(from) {
+		return false
+	}
+	return to.IsZero() || now.Before(to)
+}
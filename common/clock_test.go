@@ -0,0 +1,75 @@
+package common
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"testing"
+	"time"
+)
+
+// newTestContext returns a context whose
+// transaction timestamp is "now"
+func newTestContext(now time.Time) *TickenTxContext {
+	stub := shimtest.NewMockStub("test", nil)
+	stub.MockTransactionStart("tx")
+	stub.TxTimestamp = timestamppb.New(now)
+
+	ctx := NewTransactionContext()
+	ctx.SetStub(stub)
+	return ctx
+}
+
+func TestHasPassed(t *testing.T) {
+	now := time.Date(2023, 3, 10, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{name: "past", t: now.Add(-time.Second), want: true},
+		{name: "now", t: now, want: true},
+		{name: "future", t: now.Add(time.Second), want: false},
+		{name: "other time zone", t: now.In(time.FixedZone("ART", -3*60*60)), want: true},
+	}
+
+	ctx := newTestContext(now)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HasPassed(ctx, tt.t)
+			if err != nil {
+				t.Fatalf("HasPassed() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HasPassed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithinWindow(t *testing.T) {
+	now := time.Date(2023, 3, 10, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want bool
+	}{
+		{name: "inside", from: now.Add(-time.Hour), to: now.Add(time.Hour), want: true},
+		{name: "on start", from: now, to: now.Add(time.Hour), want: true},
+		{name: "on end", from: now.Add(-time.Hour), to: now, want: false},
+		{name: "before start", from: now.Add(time.Second), to: now.Add(time.Hour), want: false},
+		{name: "after end", from: now.Add(-time.Hour), to: now.Add(-time.Second), want: false},
+		{name: "open end", from: now.Add(-time.Hour), want: true},
+		{name: "open end before start", from: now.Add(time.Hour), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithinWindow(now, tt.from, tt.to); got != tt.want {
+				t.Errorf("WithinWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
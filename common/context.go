@@ -1,17 +1,43 @@
 package common
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"time"
 )
 
 type ITickenTxContext interface {
 	contractapi.TransactionContextInterface
 	GetInvoker(chaincode string) *Invoker
 	GetContextIdentity() (string, string, error)
+	IsPlatformAdmin() (bool, error)
+	HasRole(role string) (bool, error)
+	Now() (time.Time, error)
+	EmitStateChange(eventName string, stateChange StateChange) error
+	EmitEvent(eventName string, payload any) error
 }
 
+// RoleAttribute is the name of the certificate attribute,
+// set by the CA when enrolling the identity, that
+// holds the role of the identity in the platform
+const RoleAttribute = "ticken.role"
+
+// RoleAdmin is the value of the role attribute
+// for the identities that administrate the platform
+const RoleAdmin = "admin"
+
+// RoleTicketService is the value of the role attribute for the
+// identity of the web service that acts on behalf of the ticket
+// owners, which are not identities of the network
+const RoleTicketService = "ticket_service"
+
 type TickenTxContext struct {
 	contractapi.TransactionContext
+
+	// payloads of the chaincode events emitted in the transaction,
+	// by event name. A new context is created for every transaction
+	events map[string][]any
 }
 
 func NewTransactionContext() *TickenTxContext {
@@ -37,3 +63,75 @@ func (ctx *TickenTxContext) GetContextIdentity() (string, string, error) {
 	username := x509Cert.Subject.OrganizationalUnit[0]
 	return mspID, username, nil
 }
+
+// IsPlatformAdmin returns true if the identity that submitted
+// the transaction was enrolled with the platform admin role
+func (ctx *TickenTxContext) IsPlatformAdmin() (bool, error) {
+	return ctx.HasRole(RoleAdmin)
+}
+
+// HasRole returns true if the identity that submitted
+// the transaction was enrolled with the role "role"
+func (ctx *TickenTxContext) HasRole(role string) (bool, error) {
+	value, found, err := ctx.GetClientIdentity().GetAttributeValue(RoleAttribute)
+	if err != nil {
+		return false, err
+	}
+	return found && value == role, nil
+}
+
+// Now returns the current time as seen by the transaction. The
+// value is taken from the timestamp the client set on the proposal,
+// so it is the same on every endorser. Chaincodes must never use
+// time.Now() because each peer would compute a different result
+// and the endorsements would not match
+func (ctx *TickenTxContext) Now() (time.Time, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if txTimestamp == nil {
+		return time.Time{}, fmt.Errorf("transaction timestamp not available")
+	}
+
+	return txTimestamp.AsTime().UTC(), nil
+}
+
+// TransactionEvents is the name of the chaincode event emitted when
+// a transaction emits events with different names. Its payload maps
+// each event name to the list of its payloads
+const TransactionEvents = "TransactionEvents"
+
+// EmitStateChange emits the chaincode event "eventName"
+// with "stateChange" added to its payloads. See EmitEvent
+func (ctx *TickenTxContext) EmitStateChange(eventName string, stateChange StateChange) error {
+	return ctx.EmitEvent(eventName, stateChange)
+}
+
+// EmitEvent adds "payload" to the payloads emitted in the chaincode
+// event "eventName". Fabric keeps only the last event set in a
+// transaction, so each call sends all the events of the transaction
+// again: as the event "eventName" with the list of its payloads if
+// all of them have the same name, or as the TransactionEvents event
+func (ctx *TickenTxContext) EmitEvent(eventName string, payload any) error {
+	if ctx.events == nil {
+		ctx.events = make(map[string][]any)
+	}
+	ctx.events[eventName] = append(ctx.events[eventName], payload)
+
+	// the keys of the maps are serialized sorted, so
+	// every endorser builds the same payload
+	var eventsJSON []byte
+	var err error
+	if len(ctx.events) == 1 {
+		eventsJSON, err = json.Marshal(ctx.events[eventName])
+	} else {
+		eventName = TransactionEvents
+		eventsJSON, err = json.Marshal(ctx.events)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to serialize events: %v", err)
+	}
+
+	return ctx.GetStub().SetEvent(eventName, eventsJSON)
+}
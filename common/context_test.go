@@ -0,0 +1,50 @@
+package common
+
+import (
+	"encoding/json"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"testing"
+	"time"
+)
+
+func TestEmitEventWithDifferentNames(t *testing.T) {
+	now := time.Date(2023, 3, 10, 20, 0, 0, 0, time.UTC)
+	ctx := newTestContext(now)
+
+	stateChange := StateChange{Entity: "ticket", ID: "t1", Transition: "void", From: "issued", To: "voided", ChangedAt: now}
+	if err := ctx.EmitStateChange("TicketStatusChanged", stateChange); err != nil {
+		t.Fatalf("EmitStateChange() error = %v", err)
+	}
+	if err := EmitWaitlistOffers(ctx, []string{"offer1", "offer2"}); err != nil {
+		t.Fatalf("EmitWaitlistOffers() error = %v", err)
+	}
+
+	// fabric only keeps the last event of the transaction,
+	// so it must hold the payloads of both events
+	events := ctx.GetStub().(*shimtest.MockStub).ChaincodeEventsChannel
+	var lastEventName string
+	var lastEventPayload []byte
+	for len(events) > 0 {
+		event := <-events
+		lastEventName, lastEventPayload = event.EventName, event.Payload
+	}
+
+	if lastEventName != TransactionEvents {
+		t.Fatalf("event name = %s, want %s", lastEventName, TransactionEvents)
+	}
+
+	var payloads struct {
+		StateChanges []StateChange `json:"TicketStatusChanged"`
+		Offers       []string      `json:"WaitlistOffers"`
+	}
+	if err := json.Unmarshal(lastEventPayload, &payloads); err != nil {
+		t.Fatalf("failed to deserialize events: %v", err)
+	}
+
+	if len(payloads.StateChanges) != 1 || payloads.StateChanges[0] != stateChange {
+		t.Errorf("state changes = %+v, want [%+v]", payloads.StateChanges, stateChange)
+	}
+	if len(payloads.Offers) != 2 || payloads.Offers[0] != "offer1" || payloads.Offers[1] != "offer2" {
+		t.Errorf("offers = %v, want [offer1 offer2]", payloads.Offers)
+	}
+}
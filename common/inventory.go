@@ -0,0 +1,35 @@
+package common
+
+// InventoryCorrection holds the counters that cc-event must overwrite
+// on an event when its inventory is repaired from the tickets stored
+// in cc-ticket
+type InventoryCorrection struct {
+	Sections   []*SoldTicketsCorrection `json:"sections"`
+	SalePhases []*SalePhaseCorrection   `json:"sale_phases"`
+}
+
+// SoldTicketsCorrection is the ticket count to set on a
+// section of the event, or of one of its sessions
+type SoldTicketsCorrection struct {
+	Session     string `json:"session"`
+	Section     string `json:"section"`
+	SoldTickets int    `json:"sold_tickets"`
+
+	// tickets issued from each
+	// allotment of the section
+	Allotments []*AllotmentCorrection `json:"allotments"`
+}
+
+// AllotmentCorrection is the amount of tickets
+// issued from an allotment of the section
+type AllotmentCorrection struct {
+	Name          string `json:"name"`
+	IssuedTickets int    `json:"issued_tickets"`
+}
+
+// SalePhaseCorrection is the amount of tickets
+// sold during a sale phase of the event
+type SalePhaseCorrection struct {
+	Name        string `json:"name"`
+	SoldTickets int    `json:"sold_tickets"`
+}
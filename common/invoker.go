@@ -2,7 +2,9 @@ package common
 
 import (
 	"fmt"
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
 )
 
 type Invoker struct {
@@ -25,12 +27,48 @@ func (invoker *Invoker) Invoke(opName string, args ...string) ([]byte, error) {
 	)
 
 	if invokeResponse.Status != shim.OK {
-		return nil, fmt.Errorf(invokeResponse.Message)
+		return nil, fmt.Errorf("%s", invokeResponse.Message)
 	}
 
 	return invokeResponse.Payload, nil
 }
 
+// TopLevelChaincode returns the name of the chaincode invoked by the
+// client that submitted the transaction. When a chaincode is called
+// by another one, the name of the caller is returned, so it can be
+// used to accept operations that must only come from that chaincode
+func TopLevelChaincode(stub shim.ChaincodeStubInterface) (string, error) {
+	signedProposal, err := stub.GetSignedProposal()
+	if err != nil {
+		return "", err
+	}
+	if signedProposal == nil {
+		return "", fmt.Errorf("signed proposal not available")
+	}
+
+	var proposal pb.Proposal
+	if err := proto.Unmarshal(signedProposal.ProposalBytes, &proposal); err != nil {
+		return "", fmt.Errorf("failed to deserialize proposal: %v", err)
+	}
+
+	var payload pb.ChaincodeProposalPayload
+	if err := proto.Unmarshal(proposal.Payload, &payload); err != nil {
+		return "", fmt.Errorf("failed to deserialize proposal payload: %v", err)
+	}
+
+	var invocationSpec pb.ChaincodeInvocationSpec
+	if err := proto.Unmarshal(payload.Input, &invocationSpec); err != nil {
+		return "", fmt.Errorf("failed to deserialize invocation spec: %v", err)
+	}
+
+	chaincodeSpec := invocationSpec.GetChaincodeSpec()
+	if chaincodeSpec == nil || chaincodeSpec.GetChaincodeId() == nil {
+		return "", fmt.Errorf("proposal has no chaincode")
+	}
+
+	return chaincodeSpec.GetChaincodeId().GetName(), nil
+}
+
 func getQueryArgs(opName string, args ...string) [][]byte {
 	queryArgs := make([][]byte, len(args)+1)
 
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// MerkleLeaf returns the leaf hash used to represent "value"
+// inside a Merkle tree. Trees are built by hashing each value
+// with sha256 and then hashing every pair of nodes sorted in
+// ascending order, so proofs don't need to carry the position
+// of the sibling nodes
+func MerkleLeaf(value string) []byte {
+	leaf := sha256.Sum256([]byte(value))
+	return leaf[:]
+}
+
+// VerifyMerkleProof returns true if the "leaf" belongs to the
+// tree with root "root" using the sibling nodes in "proof",
+// ordered from the leaf up to the root
+func VerifyMerkleProof(root, leaf []byte, proof [][]byte) bool {
+	computed := leaf
+	for _, sibling := range proof {
+		computed = hashMerklePair(computed, sibling)
+	}
+	return bytes.Equal(computed, root)
+}
+
+// ParseMerkleHash decodes a hex encoded sha256 hash, with
+// or without the "0x" prefix
+func ParseMerkleHash(hexHash string) ([]byte, error) {
+	if len(hexHash) >= 2 && (hexHash[:2] == "0x" || hexHash[:2] == "0X") {
+		hexHash = hexHash[2:]
+	}
+
+	hash, err := hex.DecodeString(hexHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != sha256.Size {
+		return nil, fmt.Errorf("invalid hash length %d - expected %d bytes", len(hash), sha256.Size)
+	}
+
+	return hash, nil
+}
+
+// ParseMerkleProof decodes a list of hex encoded hashes
+func ParseMerkleProof(hexProof []string) ([][]byte, error) {
+	proof := make([][]byte, len(hexProof))
+	for i, hexNode := range hexProof {
+		node, err := ParseMerkleHash(hexNode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof node %d: %v", i, err)
+		}
+		proof[i] = node
+	}
+	return proof, nil
+}
+
+func hashMerklePair(a, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+
+	hasher := sha256.New()
+	hasher.Write(a)
+	hasher.Write(b)
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,151 @@
+package common
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildMerkleTree builds the tree of "values" the same way the
+// allowlists are built off-chain, returning its root and the proof
+// of each value. When a level has an odd amount of nodes, the last
+// one is promoted to the next level without a sibling
+func buildMerkleTree(values []string) ([]byte, [][][]byte) {
+	level := make([][]byte, len(values))
+	positions := make([]int, len(values))
+	proofs := make([][][]byte, len(values))
+	for i, value := range values {
+		level[i] = MerkleLeaf(value)
+		positions[i] = i
+	}
+
+	for len(level) > 1 {
+		for i, position := range positions {
+			sibling := position ^ 1
+			if sibling < len(level) {
+				proofs[i] = append(proofs[i], level[sibling])
+			}
+			positions[i] = position / 2
+		}
+
+		next := make([][]byte, 0)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, hashMerklePair(level[i], level[i+1]))
+		}
+		level = next
+	}
+
+	return level[0], proofs
+}
+
+func TestVerifyMerkleProof(t *testing.T) {
+	tests := []struct {
+		name   string
+		leaves int
+	}{
+		{name: "single leaf", leaves: 1},
+		{name: "two leaves", leaves: 2},
+		{name: "three leaves", leaves: 3},
+		{name: "four leaves", leaves: 4},
+		{name: "five leaves", leaves: 5},
+		{name: "seven leaves", leaves: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := make([]string, tt.leaves)
+			for i := range values {
+				values[i] = fmt.Sprintf("owner-%d", i)
+			}
+
+			root, proofs := buildMerkleTree(values)
+			for i, value := range values {
+				if !VerifyMerkleProof(root, MerkleLeaf(value), proofs[i]) {
+					t.Errorf("proof of %s was rejected", value)
+				}
+			}
+
+			for i, proof := range proofs {
+				if len(proof) == 0 {
+					continue
+				}
+				tampered := append([][]byte{MerkleLeaf("tampered")}, proof[1:]...)
+				if VerifyMerkleProof(root, MerkleLeaf(values[i]), tampered) {
+					t.Errorf("tampered proof of %s was accepted", values[i])
+				}
+			}
+
+			if VerifyMerkleProof(root, MerkleLeaf("outsider"), proofs[0]) {
+				t.Errorf("proof of an outsider was accepted")
+			}
+		})
+	}
+}
+
+func TestVerifyMerkleProofOddLevel(t *testing.T) {
+	a, b, c := MerkleLeaf("a"), MerkleLeaf("b"), MerkleLeaf("c")
+	root := hashMerklePair(hashMerklePair(a, b), c)
+
+	tests := []struct {
+		name  string
+		leaf  []byte
+		proof [][]byte
+		want  bool
+	}{
+		{name: "paired leaf", leaf: a, proof: [][]byte{b, c}, want: true},
+		{name: "paired leaf with swapped sibling", leaf: b, proof: [][]byte{a, c}, want: true},
+		{name: "promoted leaf", leaf: c, proof: [][]byte{hashMerklePair(a, b)}, want: true},
+		{name: "promoted leaf with extra node", leaf: c, proof: [][]byte{c, hashMerklePair(a, b)}, want: false},
+		{name: "missing node", leaf: a, proof: [][]byte{b}, want: false},
+		{name: "empty proof", leaf: a, proof: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyMerkleProof(root, tt.leaf, tt.proof); got != tt.want {
+				t.Errorf("VerifyMerkleProof() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMerkleHash(t *testing.T) {
+	hash := hex.EncodeToString(MerkleLeaf("owner"))
+
+	tests := []struct {
+		name    string
+		hexHash string
+		wantErr bool
+	}{
+		{name: "plain", hexHash: hash},
+		{name: "lower prefix", hexHash: "0x" + hash},
+		{name: "upper prefix", hexHash: "0X" + strings.ToUpper(hash)},
+		{name: "short", hexHash: hash[:62], wantErr: true},
+		{name: "long", hexHash: hash + "00", wantErr: true},
+		{name: "not hex", hexHash: "zz" + hash[2:], wantErr: true},
+		{name: "empty", hexHash: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMerkleHash(tt.hexHash)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseMerkleHash() returned %x, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMerkleHash() error = %v", err)
+			}
+			if hex.EncodeToString(got) != hash {
+				t.Errorf("ParseMerkleHash() = %x, want %s", got, hash)
+			}
+		})
+	}
+}
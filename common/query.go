@@ -0,0 +1,73 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRichQueryNotSupported is returned by the rich queries when
+// the peer stores the world state in LevelDB instead of CouchDB
+var ErrRichQueryNotSupported = errors.New("rich queries are not supported by the state database of the peer, CouchDB is required")
+
+// QueryPage is a page of records returned by a rich query
+type QueryPage struct {
+	Records [][]byte
+
+	// bookmark used to request the next page,
+	// empty when there are no more records
+	Bookmark string
+}
+
+// Query runs a CouchDB rich query over the records of the entity and
+// returns at most "pageSize" of them, starting from "bookmark". The
+// fields of the entity are nested in the envelope, so the selector must
+// reference them as "data.<field>". Records stored before the envelope
+// existed are not found until they are migrated
+func (schema *Schema) Query(ctx ITickenTxContext, selector map[string]any, pageSize int, bookmark string) (*QueryPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("invalid page size %d - page size must be greater than 0", pageSize)
+	}
+
+	entitySelector := map[string]any{"entity": schema.entity}
+	for field, condition := range selector {
+		entitySelector[field] = condition
+	}
+
+	queryJSON, err := json.Marshal(map[string]any{"selector": entitySelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query: %v", err)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryJSON), int32(pageSize), bookmark)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "not supported for leveldb") {
+			return nil, ErrRichQueryNotSupported
+		}
+		return nil, fmt.Errorf("failed to run query: %v", err)
+	}
+	// state databases without query engine
+	// return neither results nor an error
+	if resultsIterator == nil {
+		return nil, ErrRichQueryNotSupported
+	}
+	defer resultsIterator.Close()
+
+	page := QueryPage{Records: make([][]byte, 0)}
+	for resultsIterator.HasNext() {
+		result, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query result: %v", err)
+		}
+		page.Records = append(page.Records, result.Value)
+	}
+
+	// CouchDB returns a bookmark even after the last
+	// page, so it is only kept if the page was full
+	if metadata != nil && len(page.Records) == pageSize {
+		page.Bookmark = metadata.Bookmark
+	}
+
+	return &page, nil
+}
@@ -0,0 +1,33 @@
+package common
+
+import "math"
+
+// CurrencyRevenue is the amount collected in a currency
+type CurrencyRevenue struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+// AddRevenue adds "amount" to the revenue of "currency" in
+// "revenues", returning the list with the revenue added
+func AddRevenue(revenues []*CurrencyRevenue, currency string, amount float64) []*CurrencyRevenue {
+	for _, revenue := range revenues {
+		if revenue.Currency == currency {
+			revenue.Amount += amount
+			return revenues
+		}
+	}
+
+	return append(revenues, &CurrencyRevenue{
+		Currency: currency,
+		Amount:   amount,
+	})
+}
+
+// RoundRevenue rounds the amounts of "revenues" to two decimals,
+// removing the floating point noise of the accumulated sums
+func RoundRevenue(revenues []*CurrencyRevenue) {
+	for _, revenue := range revenues {
+		revenue.Amount = math.Round(revenue.Amount*100) / 100
+	}
+}
@@ -0,0 +1,193 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Envelope is the format in which the entities are stored in the
+// ledger. It wraps the entity with the version of its schema, so the
+// records written by older versions of a chaincode can be upgraded
+type Envelope struct {
+	Entity  string          `json:"entity"`
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Upgrade converts the JSON of an entity from
+// one version of its schema to the following one
+type Upgrade func(data json.RawMessage) (json.RawMessage, error)
+
+// Schema knows the current version of an entity and how to upgrade
+// the records stored with older versions. Records stored before the
+// envelope existed are the plain JSON of the entity, and they are
+// read as version 0
+type Schema struct {
+	entity   string
+	version  int
+	upgrades map[int]Upgrade
+}
+
+// MigrationPage is the result of rewriting
+// a page of records with the current version
+type MigrationPage struct {
+	Scanned  int `json:"scanned"`
+	Migrated int `json:"migrated"`
+
+	// key from which the next page starts,
+	// empty when there are no more records
+	Bookmark string `json:"bookmark"`
+}
+
+func NewSchema(entity string, version int) *Schema {
+	return &Schema{
+		entity:   entity,
+		version:  version,
+		upgrades: make(map[int]Upgrade),
+	}
+}
+
+// RegisterUpgrade sets the function that upgrades the records
+// of version "from" to version "from" + 1. An upgrade must be
+// registered for every version below the current one
+func (schema *Schema) RegisterUpgrade(from int, upgrade Upgrade) *Schema {
+	schema.upgrades[from] = upgrade
+	return schema
+}
+
+func (schema *Schema) Version() int {
+	return schema.version
+}
+
+// Marshal serializes the entity wrapped in
+// an envelope with the current version
+func (schema *Schema) Marshal(entity any) ([]byte, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.wrap(data)
+}
+
+// Unmarshal deserializes a record into "entity", applying the
+// upgrades from the version it was stored with to the current one.
+// The version the record was stored with is returned
+func (schema *Schema) Unmarshal(record []byte, entity any) (int, error) {
+	data, version, err := schema.upgrade(record)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := json.Unmarshal(data, entity); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Migrate rewrites with the current version the records stored with an
+// older one, reading at most "pageSize" records of the simple keys from
+// "bookmark" on. The caller must make sure that all the simple keys of
+// the chaincode hold records of this entity. The returned bookmark is
+// used to request the next page. Fabric does not allow paginated queries
+// in transactions that write, so the page is read with a range query
+func (schema *Schema) Migrate(ctx ITickenTxContext, pageSize int, bookmark string) (*MigrationPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("invalid page size %d - page size must be greater than 0", pageSize)
+	}
+
+	recordsIterator, err := ctx.GetStub().GetStateByRange(bookmark, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a records iterator: %v", err)
+	}
+	defer recordsIterator.Close()
+
+	page := MigrationPage{}
+	for recordsIterator.HasNext() {
+		record, err := recordsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record: %v", err)
+		}
+
+		// composite keys hold indexes and other
+		// entities, so they are not migrated
+		if strings.HasPrefix(record.Key, compositeKeyNamespace) {
+			continue
+		}
+
+		if page.Scanned == pageSize {
+			page.Bookmark = record.Key
+			break
+		}
+		page.Scanned += 1
+
+		data, version, err := schema.upgrade(record.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upgrade record %s: %v", record.Key, err)
+		}
+		if version == schema.version {
+			continue
+		}
+
+		envelopeJSON, err := schema.wrap(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize record %s: %v", record.Key, err)
+		}
+
+		if err := ctx.GetStub().PutState(record.Key, envelopeJSON); err != nil {
+			return nil, fmt.Errorf("failed to update record %s: %v", record.Key, err)
+		}
+		page.Migrated += 1
+	}
+
+	return &page, nil
+}
+
+// compositeKeyNamespace is the prefix that
+// fabric adds to all the composite keys
+const compositeKeyNamespace = "\x00"
+
+func (schema *Schema) wrap(data json.RawMessage) ([]byte, error) {
+	return json.Marshal(Envelope{
+		Entity:  schema.entity,
+		Version: schema.version,
+		Data:    data,
+	})
+}
+
+// upgrade returns the data of the record upgraded to the
+// current version and the version it was stored with
+func (schema *Schema) upgrade(record []byte) (json.RawMessage, int, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(record, &envelope); err != nil {
+		return nil, 0, err
+	}
+
+	data := json.RawMessage(record)
+	version := 0
+	if envelope.Entity == schema.entity && len(envelope.Data) > 0 {
+		data = envelope.Data
+		version = envelope.Version
+	}
+
+	if version > schema.version {
+		return nil, 0, fmt.Errorf("%s version %d is newer than the supported version %d", schema.entity, version, schema.version)
+	}
+
+	for v := version; v < schema.version; v++ {
+		upgrade, ok := schema.upgrades[v]
+		if !ok {
+			return nil, 0, fmt.Errorf("no upgrade registered for %s version %d", schema.entity, v)
+		}
+
+		var err error
+		data, err = upgrade(data)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to upgrade %s from version %d: %v", schema.entity, v, err)
+		}
+	}
+
+	return data, version, nil
+}
@@ -0,0 +1,173 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type testRecord struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// newTestSchema returns a schema in version 1 whose upgrade
+// from version 0 sets the type of the records without one
+func newTestSchema() *Schema {
+	return NewSchema("record", 1).RegisterUpgrade(0, func(data json.RawMessage) (json.RawMessage, error) {
+		var record map[string]any
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, err
+		}
+		if recordType, _ := record["type"].(string); len(recordType) == 0 {
+			record["type"] = "standard"
+		}
+		return json.Marshal(record)
+	})
+}
+
+func TestSchemaUnmarshal(t *testing.T) {
+	tests := []struct {
+		name        string
+		record      string
+		want        testRecord
+		wantVersion int
+		wantErr     bool
+	}{
+		{
+			name:        "v0 plain record",
+			record:      `{"name":"a"}`,
+			want:        testRecord{Name: "a", Type: "standard"},
+			wantVersion: 0,
+		},
+		{
+			name:        "v0 plain record with type",
+			record:      `{"name":"a","type":"comp"}`,
+			want:        testRecord{Name: "a", Type: "comp"},
+			wantVersion: 0,
+		},
+		{
+			name:        "v0 record of an entity with a data field",
+			record:      `{"entity":"other","version":1,"data":{"name":"a"},"name":"b"}`,
+			want:        testRecord{Name: "b", Type: "standard"},
+			wantVersion: 0,
+		},
+		{
+			name:        "v1 envelope",
+			record:      `{"entity":"record","version":1,"data":{"name":"a"}}`,
+			want:        testRecord{Name: "a"},
+			wantVersion: 1,
+		},
+		{
+			name:    "newer version",
+			record:  `{"entity":"record","version":2,"data":{"name":"a"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "upgrade failed",
+			record:  `{"entity":"record","version":0,"data":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			record:  `{"name":`,
+			wantErr: true,
+		},
+	}
+
+	schema := newTestSchema()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testRecord
+			version, err := schema.Unmarshal([]byte(tt.record), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Unmarshal() returned %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("Unmarshal() version = %d, want %d", version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestSchemaMissingUpgrade(t *testing.T) {
+	schema := NewSchema("record", 2).RegisterUpgrade(0, func(data json.RawMessage) (json.RawMessage, error) {
+		return data, nil
+	})
+
+	var record testRecord
+	if _, err := schema.Unmarshal([]byte(`{"name":"a"}`), &record); err == nil {
+		t.Errorf("Unmarshal() upgraded a record without the upgrade of version 1")
+	}
+}
+
+func TestSchemaMigrate(t *testing.T) {
+	schema := newTestSchema()
+	current, _ := schema.Marshal(testRecord{Name: "b", Type: "comp"})
+
+	// the mock stub only reads open ended ranges from the first
+	// key, so every page starts from the beginning of the ledger
+	tests := []struct {
+		name         string
+		pageSize     int
+		wantScanned  int
+		wantMigrated int
+		wantBookmark string
+	}{
+		{name: "first page", pageSize: 2, wantScanned: 2, wantMigrated: 1, wantBookmark: "c"},
+		{name: "page with all the records", pageSize: 3, wantScanned: 3, wantMigrated: 2, wantBookmark: ""},
+		{name: "page larger than the ledger", pageSize: 10, wantScanned: 3, wantMigrated: 2, wantBookmark: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(time.Now())
+			stub := ctx.GetStub()
+
+			_ = stub.PutState("a", []byte(`{"name":"a"}`))
+			_ = stub.PutState("b", current)
+			_ = stub.PutState("c", []byte(`{"name":"c","type":"comp"}`))
+
+			// composite keys hold other entities and are skipped
+			indexKey, _ := stub.CreateCompositeKey("index", []string{"a"})
+			_ = stub.PutState(indexKey, []byte{0x00})
+
+			page, err := schema.Migrate(ctx, tt.pageSize, "")
+			if err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+
+			got := fmt.Sprintf("%d/%d/%q", page.Scanned, page.Migrated, page.Bookmark)
+			want := fmt.Sprintf("%d/%d/%q", tt.wantScanned, tt.wantMigrated, tt.wantBookmark)
+			if got != want {
+				t.Errorf("Migrate() scanned/migrated/bookmark = %s, want %s", got, want)
+			}
+
+			for _, key := range []string{"a", "b", "c"}[:tt.wantScanned] {
+				var envelope Envelope
+				record, _ := stub.GetState(key)
+				if err := json.Unmarshal(record, &envelope); err != nil || envelope.Entity != "record" || envelope.Version != 1 {
+					t.Errorf("record %s was not migrated to version 1: %s", key, record)
+				}
+			}
+
+			if index, _ := stub.GetState(indexKey); len(index) != 1 || index[0] != 0x00 {
+				t.Errorf("composite key was modified: %q", index)
+			}
+		})
+	}
+
+	if _, err := schema.Migrate(newTestContext(time.Now()), 0, ""); err == nil {
+		t.Errorf("Migrate() accepted a page size of 0")
+	}
+}
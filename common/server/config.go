@@ -0,0 +1,164 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Mode is the way in which the chaincode process is run
+type Mode string
+
+const (
+	// ModeService runs the chaincode as a service: the process
+	// listens on an address and the peer connects to it
+	ModeService Mode = "service"
+
+	// ModePeer runs the chaincode launched by the peer: the
+	// process connects to the peer using the CORE_* variables
+	// that the peer sets when it starts it
+	ModePeer Mode = "peer"
+)
+
+// names of the env variables read by ConfigFromEnv
+const (
+	EnvMode         = "CHAINCODE_SERVER_MODE"
+	EnvChaincodeID  = "CHAINCODE_ID"
+	EnvAddress      = "CHAINCODE_SERVER_ADDRESS"
+	EnvTLSDisabled  = "CHAINCODE_TLS_DISABLED"
+	EnvTLSKey       = "CHAINCODE_TLS_KEY"
+	EnvTLSCert      = "CHAINCODE_TLS_CERT"
+	EnvClientCACert = "CHAINCODE_CLIENT_CA_CERT"
+
+	EnvTLSReloadInterval = "CHAINCODE_TLS_RELOAD_INTERVAL"
+	EnvHealthAddress     = "CHAINCODE_HEALTH_ADDRESS"
+	EnvShutdownTimeout   = "CHAINCODE_SHUTDOWN_TIMEOUT"
+)
+
+const defaultMode = ModeService
+const defaultTLSDisabled = "true"
+const defaultTLSReloadInterval = "1m"
+const defaultShutdownTimeout = "30s"
+
+// Config holds the settings used to run a chaincode process
+type Config struct {
+	Mode Mode
+
+	// package ID of the chaincode and address where the
+	// process listens, only used in mode "service"
+	ChaincodeID string
+	Address     string
+
+	TLS TLSConfig
+
+	// optional address of the HTTP server that exposes the
+	// liveness and readiness endpoints. Empty disables it
+	HealthAddress string
+
+	// maximum time waited for the in-flight transactions
+	// to finish when the process is asked to stop
+	ShutdownTimeout time.Duration
+}
+
+// TLSConfig holds the paths of the crypto files used
+// by the chaincode server in mode "service"
+type TLSConfig struct {
+	Disabled bool
+	KeyPath  string
+	CertPath string
+
+	// optional CA used to verify the certificate
+	// of the peer. When empty, the peer is not verified
+	ClientCACertPath string
+
+	// how often the crypto files are checked for changes, so
+	// rotated certs are used without restarting. Zero disables it
+	ReloadInterval time.Duration
+}
+
+// ConfigFromEnv reads the config from the env variables and
+// validates it. The mode defaults to "service" and TLS is
+// disabled unless CHAINCODE_TLS_DISABLED is set to false
+func ConfigFromEnv() (*Config, error) {
+	tlsDisabled, err := strconv.ParseBool(getEnvOrDefault(EnvTLSDisabled, defaultTLSDisabled))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %v", EnvTLSDisabled, err)
+	}
+
+	tlsReloadInterval, err := time.ParseDuration(getEnvOrDefault(EnvTLSReloadInterval, defaultTLSReloadInterval))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %v", EnvTLSReloadInterval, err)
+	}
+
+	shutdownTimeout, err := time.ParseDuration(getEnvOrDefault(EnvShutdownTimeout, defaultShutdownTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %v", EnvShutdownTimeout, err)
+	}
+
+	config := Config{
+		Mode:        Mode(getEnvOrDefault(EnvMode, string(defaultMode))),
+		ChaincodeID: getEnvOrDefault(EnvChaincodeID, ""),
+		Address:     getEnvOrDefault(EnvAddress, ""),
+		TLS: TLSConfig{
+			Disabled:         tlsDisabled,
+			KeyPath:          getEnvOrDefault(EnvTLSKey, ""),
+			CertPath:         getEnvOrDefault(EnvTLSCert, ""),
+			ClientCACertPath: getEnvOrDefault(EnvClientCACert, ""),
+			ReloadInterval:   tlsReloadInterval,
+		},
+		HealthAddress:   getEnvOrDefault(EnvHealthAddress, ""),
+		ShutdownTimeout: shutdownTimeout,
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Validate checks that the config has all the
+// values required by the mode it runs in
+func (config *Config) Validate() error {
+	if config.ShutdownTimeout < 0 {
+		return fmt.Errorf("invalid shutdown timeout %s - timeout can not be negative", config.ShutdownTimeout)
+	}
+
+	switch config.Mode {
+	case ModePeer:
+		return nil
+	case ModeService:
+	default:
+		return fmt.Errorf("invalid mode %q - mode must be %q or %q", config.Mode, ModeService, ModePeer)
+	}
+
+	if len(config.ChaincodeID) == 0 {
+		return fmt.Errorf("required env value %s not found", EnvChaincodeID)
+	}
+	if len(config.Address) == 0 {
+		return fmt.Errorf("required env value %s not found", EnvAddress)
+	}
+
+	if !config.TLS.Disabled {
+		if len(config.TLS.KeyPath) == 0 {
+			return fmt.Errorf("required env value %s not found", EnvTLSKey)
+		}
+		if len(config.TLS.CertPath) == 0 {
+			return fmt.Errorf("required env value %s not found", EnvTLSCert)
+		}
+		if config.TLS.ReloadInterval < 0 {
+			return fmt.Errorf("invalid TLS reload interval %s - interval can not be negative", config.TLS.ReloadInterval)
+		}
+	}
+
+	return nil
+}
+
+func getEnvOrDefault(env, defaultVal string) string {
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		value = defaultVal
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// healthServer exposes the liveness and readiness of the chaincode
+// to the orchestrator. "/healthz" answers while the process is up,
+// and "/readyz" answers once the chaincode serves connections or starts
+// to connect to the peer, depending on the mode, and until it starts
+// to shut down. A nil health server does nothing
+type healthServer struct {
+	server *http.Server
+
+	// 1 when ready, accessed atomically
+	ready int32
+}
+
+func newHealthServer(address string) *healthServer {
+	if len(address) == 0 {
+		return nil
+	}
+
+	health := healthServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&health.ready) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	health.server = &http.Server{Addr: address, Handler: mux}
+	return &health
+}
+
+// start listens on the health address and serves
+// the endpoints in the background
+func (health *healthServer) start() error {
+	if health == nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", health.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		err := health.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("error serving health endpoints: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (health *healthServer) setReady(ready bool) {
+	if health == nil {
+		return
+	}
+
+	value := int32(0)
+	if ready {
+		value = 1
+	}
+	atomic.StoreInt32(&health.ready, value)
+}
+
+func (health *healthServer) shutdown(ctx context.Context) {
+	if health == nil {
+		return
+	}
+
+	if err := health.server.Shutdown(ctx); err != nil {
+		log.Printf("error stopping health endpoints: %v", err)
+	}
+}
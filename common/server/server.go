@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maximum size of the messages exchanged
+// with the peer, the same used by the shim
+const maxMessageSize = 100 * 1024 * 1024
+
+const healthShutdownTimeout = 5 * time.Second
+
+// Run reads the config from the env variables and
+// runs the chaincode made of "contract" with it
+func Run(contract contractapi.ContractInterface) error {
+	config, err := ConfigFromEnv()
+	if err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	return Start(contract, config)
+}
+
+// Start creates the chaincode made of "contract" and runs it in the
+// mode of the config. It blocks until the chaincode stops. When the
+// process receives SIGTERM or SIGINT, the chaincode is reported as not
+// ready, the transactions in flight are waited for and then the server
+// is stopped, returning nil
+func Start(contract contractapi.ContractInterface, config *Config) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	cc, err := contractapi.NewChaincode(contract)
+	if err != nil {
+		return fmt.Errorf("error creating chaincode: %v", err)
+	}
+	tracker := newTxTracker(cc)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	health := newHealthServer(config.HealthAddress)
+	if err := health.start(); err != nil {
+		return fmt.Errorf("error starting health endpoints: %v", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), healthShutdownTimeout)
+		defer cancel()
+		health.shutdown(shutdownCtx)
+	}()
+
+	// the chaincode is reported as ready once the server accepts
+	// connections in mode "service". In mode "peer", the shim does
+	// not tell when the peer accepts the chaincode, so it is reported
+	// as ready once it starts to connect
+	ready := func() { health.setReady(true) }
+
+	var serve func() error
+	var halt func()
+	if config.Mode == ModePeer {
+		// the process can not stop the connection
+		// opened with the peer, it ends when it exits
+		serve = func() error {
+			ready()
+			return shim.Start(tracker)
+		}
+		halt = func() {}
+	} else {
+		grpcServer, listener, err := newGRPCServer(ctx, config, tracker)
+		if err != nil {
+			return err
+		}
+		serve = func() error { return grpcServer.Serve(&servingListener{Listener: listener, serving: ready}) }
+		halt = grpcServer.Stop
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve()
+	}()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			return fmt.Errorf("error serving chaincode: %v", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	log.Printf("stopping chaincode, waiting for the transactions in flight")
+	health.setReady(false)
+	if !tracker.drain(config.ShutdownTimeout) {
+		log.Printf("shutdown timeout reached with transactions in flight")
+	}
+	halt()
+
+	return nil
+}
+
+// newGRPCServer creates the server used in mode "service" and the
+// listener on the address of the config. It has the same options as
+// the one created by shim.ChaincodeServer, but its certs are reloaded
+// while "ctx" is not done and it can be stopped
+func newGRPCServer(ctx context.Context, config *Config, cc shim.Chaincode) (*grpc.Server, net.Listener, error) {
+	serverOpts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    1 * time.Minute,
+			Timeout: 20 * time.Second,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             1 * time.Minute,
+			PermitWithoutStream: true,
+		}),
+		grpc.MaxSendMsgSize(maxMessageSize),
+		grpc.MaxRecvMsgSize(maxMessageSize),
+		grpc.ConnectionTimeout(5 * time.Second),
+	}
+
+	if !config.TLS.Disabled {
+		reloader, err := newCertReloader(&config.TLS)
+		if err != nil {
+			return nil, nil, err
+		}
+		if config.TLS.ReloadInterval > 0 {
+			go reloader.watch(ctx, config.TLS.ReloadInterval)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(reloader.serverConfig())))
+	}
+
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listening on %s: %v", config.Address, err)
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
+	pb.RegisterChaincodeServer(grpcServer, &shim.ChaincodeServer{
+		CCID: config.ChaincodeID,
+		CC:   cc,
+	})
+
+	return grpcServer, listener, nil
+}
+
+// servingListener calls "serving" the first time the gRPC
+// server waits for a connection, which happens once it serves
+type servingListener struct {
+	net.Listener
+
+	serving func()
+	once    sync.Once
+}
+
+func (listener *servingListener) Accept() (net.Conn, error) {
+	listener.once.Do(listener.serving)
+	return listener.Listener.Accept()
+}
+
+// properties reads the crypto files of the config
+func (config *TLSConfig) properties() (shim.TLSProperties, error) {
+	props := shim.TLSProperties{Disabled: config.Disabled}
+
+	var err error
+	if !config.Disabled {
+		props.Key, err = os.ReadFile(config.KeyPath)
+		if err != nil {
+			return props, fmt.Errorf("error while reading the TLS key: %v", err)
+		}
+		props.Cert, err = os.ReadFile(config.CertPath)
+		if err != nil {
+			return props, fmt.Errorf("error while reading the TLS cert: %v", err)
+		}
+	}
+
+	// when empty, the peer cert is not verified
+	if len(config.ClientCACertPath) > 0 {
+		props.ClientCACerts, err = os.ReadFile(config.ClientCACertPath)
+		if err != nil {
+			return props, fmt.Errorf("error while reading the client CA cert: %v", err)
+		}
+	}
+
+	return props, nil
+}
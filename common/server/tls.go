@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves the TLS config built from the crypto files of
+// the config, and builds it again when the files change, so the certs
+// can be rotated without restarting the chaincode
+type certReloader struct {
+	config *TLSConfig
+
+	mu       sync.RWMutex
+	current  *tls.Config
+	modTimes []time.Time
+}
+
+func newCertReloader(config *TLSConfig) (*certReloader, error) {
+	reloader := certReloader{config: config}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return &reloader, nil
+}
+
+// serverConfig returns the config passed to the gRPC server. Every
+// handshake asks the reloader for the config loaded at that moment
+func (reloader *certReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			reloader.mu.RLock()
+			defer reloader.mu.RUnlock()
+			return reloader.current, nil
+		},
+	}
+}
+
+// watch checks the crypto files every "interval" until the context is
+// done. If they can not be loaded, the previous config is kept
+func (reloader *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := reloader.changed()
+		if err != nil {
+			log.Printf("error checking the TLS files: %v", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		if err := reloader.reload(); err != nil {
+			log.Printf("error reloading the TLS files, the previous ones are kept: %v", err)
+			continue
+		}
+		log.Printf("TLS files reloaded")
+	}
+}
+
+func (reloader *certReloader) paths() []string {
+	paths := []string{reloader.config.KeyPath, reloader.config.CertPath}
+	if len(reloader.config.ClientCACertPath) > 0 {
+		paths = append(paths, reloader.config.ClientCACertPath)
+	}
+	return paths
+}
+
+func (reloader *certReloader) changed() (bool, error) {
+	modTimes, err := getModTimes(reloader.paths())
+	if err != nil {
+		return false, err
+	}
+
+	reloader.mu.RLock()
+	defer reloader.mu.RUnlock()
+	for i, modTime := range modTimes {
+		if !modTime.Equal(reloader.modTimes[i]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reload builds the TLS config from the crypto files, following
+// the properties used by the peer and by shim.ChaincodeServer
+func (reloader *certReloader) reload() error {
+	modTimes, err := getModTimes(reloader.paths())
+	if err != nil {
+		return err
+	}
+
+	props, err := reloader.config.properties()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.X509KeyPair(props.Cert, props.Key)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS key pair: %v", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:             tls.VersionTLS12,
+		Certificates:           []tls.Certificate{cert},
+		SessionTicketsDisabled: true,
+		NextProtos:             []string{"h2"},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+		},
+	}
+
+	// the peer is only verified if a CA is given
+	if props.ClientCACerts != nil {
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(props.ClientCACerts) {
+			return fmt.Errorf("failed to parse the client CA cert")
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+	reloader.current = config
+	reloader.modTimes = modTimes
+
+	return nil
+}
+
+func getModTimes(paths []string) ([]time.Time, error) {
+	modTimes := make([]time.Time, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"sync"
+	"time"
+)
+
+// txTracker wraps the chaincode to count the transactions in
+// flight, so the process can wait for them before stopping
+type txTracker struct {
+	cc shim.Chaincode
+
+	mu       sync.Mutex
+	idle     *sync.Cond
+	inFlight int
+	closed   bool
+}
+
+func newTxTracker(cc shim.Chaincode) *txTracker {
+	tracker := txTracker{cc: cc}
+	tracker.idle = sync.NewCond(&tracker.mu)
+	return &tracker
+}
+
+func (tracker *txTracker) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	if !tracker.begin() {
+		return shim.Error("chaincode is shutting down")
+	}
+	defer tracker.end()
+
+	return tracker.cc.Init(stub)
+}
+
+func (tracker *txTracker) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	if !tracker.begin() {
+		return shim.Error("chaincode is shutting down")
+	}
+	defer tracker.end()
+
+	return tracker.cc.Invoke(stub)
+}
+
+func (tracker *txTracker) begin() bool {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if tracker.closed {
+		return false
+	}
+	tracker.inFlight += 1
+	return true
+}
+
+func (tracker *txTracker) end() {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.inFlight -= 1
+	if tracker.inFlight == 0 {
+		tracker.idle.Broadcast()
+	}
+}
+
+// drain rejects the new transactions and waits until the ones in
+// flight end. It returns false if "timeout" passed with transactions
+// still in flight
+func (tracker *txTracker) drain(timeout time.Duration) bool {
+	expired := false
+	timer := time.AfterFunc(timeout, func() {
+		tracker.mu.Lock()
+		defer tracker.mu.Unlock()
+		expired = true
+		tracker.idle.Broadcast()
+	})
+	defer timer.Stop()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.closed = true
+	for tracker.inFlight > 0 && !expired {
+		tracker.idle.Wait()
+	}
+
+	return tracker.inFlight == 0
+}
@@ -0,0 +1,173 @@
+package common
+
+import "time"
+
+// Guard checks a condition over the entity that must hold
+// for a transition to be fired, returning an error if not
+type Guard[T any] func(ctx ITickenTxContext, entity T) error
+
+// Transition moves an entity from any of the states in "From" to
+// the state "To". The transition is only fired if the caller is
+// authorized and all its guards pass, in that order
+type Transition[T any, S ~string] struct {
+	Name string
+	From []S
+	To   S
+
+	// checks that the identity that submitted the
+	// transaction can fire the transition (can be nil)
+	Authorize Guard[T]
+
+	// conditions over the entity checked
+	// after the state of the entity
+	Guards []Guard[T]
+
+	// side effects applied to the entity
+	// once its state was updated (can be nil)
+	Action func(ctx ITickenTxContext, entity T) error
+
+	// name of the chaincode event emitted when the transition is
+	// fired, with the list of the StateChange of the transaction
+	// as payload. Empty means that no chaincode event is emitted
+	Event string
+}
+
+// StateChange describes a fired transition. All the state changes
+// of a transaction are sent together in the same chaincode event
+type StateChange struct {
+	Entity     string    `json:"entity"`
+	ID         string    `json:"id"`
+	Transition string    `json:"transition"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// StateMachineConfig describes how a StateMachine reads
+// and identifies the entities it drives
+type StateMachineConfig[T any, S ~string] struct {
+	// name of the entity used in the
+	// errors and in the StateChange
+	Entity string
+
+	// ID returns the identifier of the entity
+	ID func(entity T) string
+
+	// State returns a pointer to the field
+	// of the entity that holds its state
+	State func(entity T) *S
+
+	// Errorf builds the errors of the state machine,
+	// so they follow the format of the chaincode
+	Errorf func(format string, args ...any) error
+
+	Transitions []*Transition[T, S]
+}
+
+// StateMachine drives the state of an entity through a
+// declarative table of transitions. The state machine does
+// not store the entity, so the caller must save it after
+// firing a transition
+type StateMachine[T any, S ~string] struct {
+	config      StateMachineConfig[T, S]
+	transitions map[string]*Transition[T, S]
+}
+
+func NewStateMachine[T any, S ~string](config StateMachineConfig[T, S]) *StateMachine[T, S] {
+	transitions := make(map[string]*Transition[T, S])
+	for _, transition := range config.Transitions {
+		transitions[transition.Name] = transition
+	}
+
+	return &StateMachine[T, S]{
+		config:      config,
+		transitions: transitions,
+	}
+}
+
+// Fire applies the transition "name" to the entity. The caller
+// authorization is checked first, then the current state of the
+// entity and finally the guards of the transition. If all of them
+// pass, the state is updated, the action of the transition is run
+// and its chaincode event is emitted
+func (sm *StateMachine[T, S]) Fire(ctx ITickenTxContext, name string, entity T) error {
+	transition, ok := sm.transitions[name]
+	if !ok {
+		return sm.config.Errorf("%s has no transition %s", sm.config.Entity, name)
+	}
+
+	if transition.Authorize != nil {
+		if err := transition.Authorize(ctx, entity); err != nil {
+			return err
+		}
+	}
+
+	state := sm.config.State(entity)
+	from := *state
+
+	if from == transition.To {
+		return sm.config.Errorf("%s %s already is on status %s", sm.config.Entity, sm.config.ID(entity), from)
+	}
+
+	if !sm.canFire(transition, from) {
+		return sm.config.Errorf("%s cant go from %s to %s", sm.config.Entity, from, transition.To)
+	}
+
+	for _, guard := range transition.Guards {
+		if err := guard(ctx, entity); err != nil {
+			return err
+		}
+	}
+
+	*state = transition.To
+
+	if transition.Action != nil {
+		if err := transition.Action(ctx, entity); err != nil {
+			return err
+		}
+	}
+
+	if len(transition.Event) == 0 {
+		return nil
+	}
+
+	now, err := ctx.Now()
+	if err != nil {
+		return sm.config.Errorf("failed to get transaction time: %v", err)
+	}
+
+	stateChange := StateChange{
+		Entity:     sm.config.Entity,
+		ID:         sm.config.ID(entity),
+		Transition: transition.Name,
+		From:       string(from),
+		To:         string(transition.To),
+		ChangedAt:  now,
+	}
+
+	if err := ctx.EmitStateChange(transition.Event, stateChange); err != nil {
+		return sm.config.Errorf("failed to emit state change: %v", err)
+	}
+
+	return nil
+}
+
+// CanFire returns true if the transition "name" can
+// be fired from the current state of the entity. The
+// authorization and the guards are not checked
+func (sm *StateMachine[T, S]) CanFire(name string, entity T) bool {
+	transition, ok := sm.transitions[name]
+	if !ok {
+		return false
+	}
+	return sm.canFire(transition, *sm.config.State(entity))
+}
+
+func (sm *StateMachine[T, S]) canFire(transition *Transition[T, S], from S) bool {
+	for _, state := range transition.From {
+		if state == from {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testStatus string
+
+const (
+	testStatusDraft    testStatus = "draft"
+	testStatusOnSale   testStatus = "on_sale"
+	testStatusFinished testStatus = "finished"
+)
+
+type testEntity struct {
+	ID     string
+	Status testStatus
+
+	// values checked by the guards of the transitions
+	Authorized bool
+	Ready      bool
+
+	// amount of times the action was run
+	Actions int
+}
+
+const testStatusChangedEvent = "StatusChanged"
+
+func newTestStateMachine() *StateMachine[*testEntity, testStatus] {
+	authorize := func(ctx ITickenTxContext, entity *testEntity) error {
+		if !entity.Authorized {
+			return fmt.Errorf("not authorized")
+		}
+		return nil
+	}
+
+	return NewStateMachine(StateMachineConfig[*testEntity, testStatus]{
+		Entity: "entity",
+		ID:     func(entity *testEntity) string { return entity.ID },
+		State:  func(entity *testEntity) *testStatus { return &entity.Status },
+		Errorf: fmt.Errorf,
+		Transitions: []*Transition[*testEntity, testStatus]{
+			{
+				Name:      "sell",
+				From:      []testStatus{testStatusDraft},
+				To:        testStatusOnSale,
+				Authorize: authorize,
+				Guards: []Guard[*testEntity]{
+					func(ctx ITickenTxContext, entity *testEntity) error {
+						if !entity.Ready {
+							return fmt.Errorf("not ready")
+						}
+						return nil
+					},
+				},
+				Action: func(ctx ITickenTxContext, entity *testEntity) error {
+					entity.Actions += 1
+					return nil
+				},
+				Event: testStatusChangedEvent,
+			},
+			{
+				Name:  "finish",
+				From:  []testStatus{testStatusOnSale},
+				To:    testStatusFinished,
+				Event: testStatusChangedEvent,
+			},
+		},
+	})
+}
+
+func TestStateMachineFireRejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		transition string
+		entity     testEntity
+		wantErr    string
+	}{
+		{
+			name:       "unknown transition",
+			transition: "start",
+			entity:     testEntity{ID: "e1", Status: testStatusDraft, Authorized: true, Ready: true},
+			wantErr:    "entity has no transition start",
+		},
+		{
+			name:       "unauthorized caller",
+			transition: "sell",
+			entity:     testEntity{ID: "e1", Status: testStatusDraft, Ready: true},
+			wantErr:    "not authorized",
+		},
+		{
+			name:       "authorization is checked before the status",
+			transition: "sell",
+			entity:     testEntity{ID: "e1", Status: testStatusFinished, Ready: true},
+			wantErr:    "not authorized",
+		},
+		{
+			name:       "already on the target status",
+			transition: "sell",
+			entity:     testEntity{ID: "e1", Status: testStatusOnSale, Authorized: true, Ready: true},
+			wantErr:    "entity e1 already is on status on_sale",
+		},
+		{
+			name:       "invalid source status",
+			transition: "sell",
+			entity:     testEntity{ID: "e1", Status: testStatusFinished, Authorized: true, Ready: true},
+			wantErr:    "entity cant go from finished to on_sale",
+		},
+		{
+			name:       "guard failed",
+			transition: "sell",
+			entity:     testEntity{ID: "e1", Status: testStatusDraft, Authorized: true},
+			wantErr:    "not ready",
+		},
+	}
+
+	sm := newTestStateMachine()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(time.Now())
+			entity := tt.entity
+
+			err := sm.Fire(ctx, tt.transition, &entity)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Fire() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if entity.Status != tt.entity.Status {
+				t.Errorf("status = %s, want it unchanged on %s", entity.Status, tt.entity.Status)
+			}
+			if entity.Actions != 0 {
+				t.Errorf("action was run %d times on a rejected transition", entity.Actions)
+			}
+			if events := len(ctx.GetStub().(*shimtest.MockStub).ChaincodeEventsChannel); events != 0 {
+				t.Errorf("%d events were emitted on a rejected transition", events)
+			}
+		})
+	}
+}
+
+func TestStateMachineFire(t *testing.T) {
+	now := time.Date(2023, 3, 10, 20, 0, 0, 0, time.UTC)
+	ctx := newTestContext(now)
+	sm := newTestStateMachine()
+	entity := &testEntity{ID: "e1", Status: testStatusDraft, Authorized: true, Ready: true}
+
+	if !sm.CanFire("sell", entity) || sm.CanFire("finish", entity) || sm.CanFire("start", entity) {
+		t.Fatalf("CanFire() does not match the status %s", entity.Status)
+	}
+
+	for _, transition := range []string{"sell", "finish"} {
+		if err := sm.Fire(ctx, transition, entity); err != nil {
+			t.Fatalf("Fire(%s) error = %v", transition, err)
+		}
+	}
+
+	if entity.Status != testStatusFinished {
+		t.Errorf("status = %s, want %s", entity.Status, testStatusFinished)
+	}
+	if entity.Actions != 1 {
+		t.Errorf("action was run %d times, want 1", entity.Actions)
+	}
+
+	// fabric only keeps the last event of the transaction,
+	// so it must hold the changes of both transitions
+	events := ctx.GetStub().(*shimtest.MockStub).ChaincodeEventsChannel
+	var lastEventPayload []byte
+	for len(events) > 0 {
+		event := <-events
+		if event.EventName != testStatusChangedEvent {
+			t.Errorf("event name = %s, want %s", event.EventName, testStatusChangedEvent)
+		}
+		lastEventPayload = event.Payload
+	}
+
+	var stateChanges []StateChange
+	if err := json.Unmarshal(lastEventPayload, &stateChanges); err != nil {
+		t.Fatalf("failed to deserialize state changes: %v", err)
+	}
+
+	want := []StateChange{
+		{Entity: "entity", ID: "e1", Transition: "sell", From: "draft", To: "on_sale", ChangedAt: now},
+		{Entity: "entity", ID: "e1", Transition: "finish", From: "on_sale", To: "finished", ChangedAt: now},
+	}
+	if len(stateChanges) != len(want) {
+		t.Fatalf("last event has %d state changes, want %d", len(stateChanges), len(want))
+	}
+	for i := range want {
+		if stateChanges[i] != want[i] {
+			t.Errorf("state change %d = %+v, want %+v", i, stateChanges[i], want[i])
+		}
+	}
+}
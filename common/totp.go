@@ -0,0 +1,69 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// TOTPStep is the duration of each window in which
+// a time based code is valid
+const TOTPStep = 30 * time.Second
+
+// TOTPDigits is the amount of digits of the codes
+const TOTPDigits = 6
+
+// TOTPSkew is the amount of windows before and after
+// the current one that are also accepted, to tolerate
+// the clock difference between the device that shows
+// the code and the client that submits the transaction
+const TOTPSkew = 1
+
+// TOTPCode returns the code of the window that contains the
+// moment "t" for the "secret", following RFC 6238 (HMAC-SHA1)
+func TOTPCode(secret []byte, t time.Time) string {
+	return hotpCode(secret, uint64(t.Unix()/int64(TOTPStep/time.Second)))
+}
+
+// VerifyTOTP returns true if "code" is the code of the "secret"
+// for the window that contains the moment "t" or any of the
+// TOTPSkew windows around it
+func VerifyTOTP(secret []byte, code string, t time.Time) bool {
+	if len(code) != TOTPDigits {
+		return false
+	}
+
+	counter := t.Unix() / int64(TOTPStep/time.Second)
+	for offset := int64(-TOTPSkew); offset <= TOTPSkew; offset++ {
+		expected := hotpCode(secret, uint64(counter+offset))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hotpCode computes the HOTP value defined in RFC 4226
+func hotpCode(secret []byte, counter uint64) string {
+	counterBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(counterBytes, counter)
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counterBytes)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", TOTPDigits, value%modulo)
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 secret of the test vectors of RFC 6238
+var rfc6238Secret = []byte("12345678901234567890")
+
+func TestTOTPCode(t *testing.T) {
+	// the RFC vectors have 8 digits, so
+	// the codes are their last 6 digits
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{name: "94287082", unix: 59, want: "287082"},
+		{name: "07081804", unix: 1111111109, want: "081804"},
+		{name: "14050471", unix: 1111111111, want: "050471"},
+		{name: "89005924", unix: 1234567890, want: "005924"},
+		{name: "69279037", unix: 2000000000, want: "279037"},
+		{name: "65353130", unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0)); got != tt.want {
+				t.Errorf("TOTPCode() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	// code of the window [1111111110, 1111111140)
+	const code = "050471"
+	windowStart := time.Unix(1111111110, 0)
+
+	tests := []struct {
+		name string
+		code string
+		t    time.Time
+		want bool
+	}{
+		{name: "same window", code: code, t: windowStart.Add(29 * time.Second), want: true},
+		{name: "previous window", code: code, t: windowStart.Add(-TOTPStep), want: true},
+		{name: "next window", code: code, t: windowStart.Add(TOTPStep), want: true},
+		{name: "two windows before", code: code, t: windowStart.Add(-TOTPStep - time.Second), want: false},
+		{name: "two windows after", code: code, t: windowStart.Add(2 * TOTPStep), want: false},
+		{name: "wrong code", code: "050472", t: windowStart, want: false},
+		{name: "short code", code: "50471", t: windowStart, want: false},
+		{name: "rfc 8 digits code", code: "14050471", t: windowStart, want: false},
+		{name: "empty code", code: "", t: windowStart, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyTOTP(rfc6238Secret, tt.code, tt.t); got != tt.want {
+				t.Errorf("VerifyTOTP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
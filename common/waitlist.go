@@ -0,0 +1,20 @@
+package common
+
+// WaitlistOffersEvent is the name of the chaincode event emitted
+// when released seats are offered to waitlisted owners. Its payload
+// is the list of offers made in the transaction. Fabric only keeps
+// the events of the chaincode invoked by the client, so the offers
+// made by cc-event while cc-ticket calls it are emitted again by
+// cc-ticket
+const WaitlistOffersEvent = "WaitlistOffers"
+
+// EmitWaitlistOffers adds the offers made to
+// waitlisted owners to the WaitlistOffersEvent
+func EmitWaitlistOffers[T any](ctx ITickenTxContext, offers []T) error {
+	for _, offer := range offers {
+		if err := ctx.EmitEvent(WaitlistOffersEvent, offer); err != nil {
+			return err
+		}
+	}
+	return nil
+}